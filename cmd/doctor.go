@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/nxtcoder17/fwatcher/pkg/watcher"
+	"github.com/urfave/cli/v3"
+)
+
+func doctorCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "doctor",
+		Usage: "reports what would be watched, against the inotify limits",
+		Flags: watcherFlags(),
+		Action: func(ctx context.Context, c *cli.Command) error {
+			d, err := watcher.Diagnose(watcherArgs(c))
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("directories to watch: %d\n", d.Directories)
+			if len(d.WatchTargets) > 0 {
+				fmt.Printf("files (and paths not existing yet) to watch: %s\n", strings.Join(d.WatchTargets, ", "))
+			}
+
+			// INFO: ignored directories are grouped by their names, as node_modules like directories show up in every package
+			ignored := map[string]int{}
+			for _, dir := range d.IgnoredDirs {
+				ignored[filepath.Base(dir)]++
+			}
+			names := make([]string, 0, len(ignored))
+			for name := range ignored {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			fmt.Printf("ignored directories: %d\n", len(d.IgnoredDirs))
+			for _, name := range names {
+				fmt.Printf("  %s (%d)\n", name, ignored[name])
+			}
+
+			if d.Limits == nil {
+				fmt.Println("inotify limits: not available on this system")
+				return nil
+			}
+
+			fmt.Println("inotify limits:")
+			if d.Usage != nil {
+				fmt.Printf("  max_user_watches: %d (in use: %d)\n", d.Limits.MaxUserWatches, d.Usage.Watches)
+				fmt.Printf("  max_user_instances: %d (in use: %d)\n", d.Limits.MaxUserInstances, d.Usage.Instances)
+			} else {
+				fmt.Printf("  max_user_watches: %d\n", d.Limits.MaxUserWatches)
+				fmt.Printf("  max_user_instances: %d\n", d.Limits.MaxUserInstances)
+			}
+
+			if !d.WithinLimits() {
+				fmt.Fprintf(os.Stderr, "\ninotify watch limit is too low, %s\n", d.RaiseLimitHint())
+				return cli.Exit("", 1)
+			}
+
+			fmt.Println("\nall good, directories to watch fit in the inotify watch limit")
+			return nil
+		},
+	}
+}
@@ -2,14 +2,22 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net"
 	"os"
 	"os/exec"
 	"os/signal"
+	"regexp"
+	"runtime"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
+	"github.com/nxtcoder17/fwatcher/pkg/dotenv"
 	"github.com/nxtcoder17/fwatcher/pkg/executor"
+	"github.com/nxtcoder17/fwatcher/pkg/procfile"
 	"github.com/nxtcoder17/fwatcher/pkg/watcher"
 	"github.com/nxtcoder17/go.pkgs/log"
 	"github.com/urfave/cli/v3"
@@ -21,49 +29,136 @@ var (
 )
 
 func main() {
+	// INFO: commands with umask, or resource limits, are started via fwatcher's own executable, as a shim
+	executor.RunShim()
+
 	cmd := &cli.Command{
 		Name:                   ProgramName,
 		UseShortOptionHandling: true,
 		Usage:                  "a simple tool to run things on filesystem change events",
 		ArgsUsage:              "<Command To Run>",
 		Version:                Version,
-		Flags: []cli.Flag{
+		Commands: []*cli.Command{
+			doctorCommand(),
+		},
+		Flags: append([]cli.Flag{
 			&cli.BoolFlag{
 				Name: "debug",
 			},
 
-			&cli.StringFlag{
+			&cli.StringSliceFlag{
 				Name:    "command",
-				Usage:   "[command to run]",
-				Value:   "echo hi",
+				Usage:   "[command to run] (with shell), can be specified multiple times, prefix with service: (or, service(<addr>):) for a long running service",
 				Aliases: []string{"c"},
 			},
 
+			&cli.StringFlag{
+				Name:    "shell",
+				Usage:   "[shell] to run commands with, as <shell> -c <command>",
+				Value:   "sh",
+				Sources: cli.EnvVars("FWATCHER_SHELL"),
+			},
+
+			&cli.StringSliceFlag{
+				Name:  "env-file",
+				Usage: "[dotenv file] to load env of commands from (later ones override earlier ones), commands restart as it changes, can be specified multiple times",
+			},
+
+			&cli.StringFlag{
+				Name:  "dir",
+				Usage: "[dir] working directory, to run commands in",
+			},
+
+			&cli.StringFlag{
+				Name:  "user",
+				Usage: "[user[:group]] to run commands as (needs privileges)",
+			},
+
+			&cli.StringFlag{
+				Name:  "umask",
+				Usage: "[umask] (octal, like 027) to run commands with",
+			},
+
 			&cli.StringSliceFlag{
-				Name:    "watch",
-				Usage:   "[dir] (to watch) | -[dir] (to ignore)",
-				Value:   []string{"."},
-				Aliases: []string{"w"},
+				Name:  "limit",
+				Usage: "[nofile=N|as=SIZE|cpu=DURATION] resource limit of commands (like as=2G), can be specified multiple times",
 			},
 
 			&cli.StringSliceFlag{
-				Name:     "ext",
-				Usage:    "[ext] (to watch) | -[ext] (to ignore)",
-				Required: false,
-				Aliases:  []string{"e"},
+				Name:  "port",
+				Usage: "[port] (or, with --procfile, [entry=port]) that command binds, it is (re)started only once port is free, can be specified multiple times",
 			},
 
-			// &cli.StringSliceFlag{
-			// 	Name:    "exclude",
-			// 	Usage:   "exclude this directory",
-			// 	Aliases: []string{"x"},
-			// },
+			&cli.StringFlag{
+				Name:  "port-timeout",
+				Usage: "how long to wait for ports to be released, before failing",
+				Value: "5s",
+			},
+
+			&cli.StringFlag{
+				Name:  "ready-timeout",
+				Usage: "how long to wait for a service (with an address) to get ready, before stopping it, and failing",
+				Value: "30s",
+			},
+
+			&cli.BoolFlag{
+				Name:  "no-cgroup",
+				Usage: "do not place commands in cgroups (v2, linux only), i.e. only their process groups are killed on restart",
+			},
+
+			&cli.BoolFlag{
+				Name:  "no-subreaper",
+				Usage: "do not track daemonized processes of commands (linux only), i.e. they are not killed on restart",
+			},
+
+			&cli.StringFlag{
+				Name:  "memory-max",
+				Usage: "[SIZE] memory limit of every command (like 512M), enforced via its cgroup",
+			},
+
+			&cli.FloatFlag{
+				Name:  "cpu-max",
+				Usage: "[cpus] cpu limit of every command (like 1.5), enforced via its cgroup",
+			},
+
+			&cli.BoolFlag{
+				Name:  "parallel",
+				Usage: "run commands in parallel, with their output prefixed by command",
+			},
 
 			&cli.StringSliceFlag{
-				Name:    "ignore-list",
-				Usage:   "disables ignoring from default ignore list",
-				Value:   watcher.DefaultIgnoreList,
-				Aliases: []string{"I"},
+				Name:  "pattern",
+				Usage: "[pattern] (or, [n=pattern] for n-th command, counting from 1) restart commands, only on changes matching pattern (like *.proto, or api/**), can be specified multiple times",
+			},
+
+			&cli.BoolFlag{
+				Name:  "no-color",
+				Usage: "disables colored output prefixes (also disabled, if NO_COLOR is set)",
+			},
+
+			&cli.BoolFlag{
+				Name:  "timestamps",
+				Usage: "prefixes output of parallel commands with timestamps",
+			},
+
+			&cli.StringFlag{
+				Name:  "procfile",
+				Usage: "[Procfile] run its entries in parallel, instead of the command",
+			},
+
+			&cli.StringSliceFlag{
+				Name:  "scope",
+				Usage: "[entry=dir] restart procfile entry, only on changes inside dir, can be specified multiple times",
+			},
+
+			&cli.StringSliceFlag{
+				Name:  "depends-on",
+				Usage: "[entry=dependency] start procfile entry, only after its dependency finishes successfully, can be specified multiple times",
+			},
+
+			&cli.StringFlag{
+				Name:  "build",
+				Usage: "[build command] (run with shell) before the command, command is restarted only if build succeeds",
 			},
 
 			&cli.StringFlag{
@@ -72,9 +167,35 @@ func main() {
 				Value: "100ms",
 			},
 
+			&cli.BoolFlag{
+				Name:  "pty",
+				Usage: "run commands attached to a pseudo terminal, so that they keep their colors (their stderr gets merged into stdout)",
+			},
+
 			&cli.BoolFlag{
 				Name:  "interactive",
-				Usage: "interactive mode, with stdin",
+				Usage: "interactive mode, commands get stdin, and run in foreground of the terminal",
+			},
+
+			&cli.StringFlag{
+				Name:  "on-busy",
+				Usage: "[restart|queue|ignore] what to do on changes, while command is still running",
+				Value: string(executor.OnBusyRestart),
+			},
+
+			&cli.BoolFlag{
+				Name:  "no-initial-run",
+				Usage: "run the command only after the first change, instead of on start",
+			},
+
+			&cli.BoolFlag{
+				Name:  "once",
+				Usage: "run the command once, and exit with its exit code",
+			},
+
+			&cli.BoolFlag{
+				Name:  "until-success",
+				Usage: "re-run the command on changes, until it succeeds, and then exit",
 			},
 
 			&cli.StringFlag{
@@ -82,7 +203,7 @@ func main() {
 				HideDefault: false,
 				Usage:       "run watcher with Server Side Events (SSE) enabled",
 			},
-		},
+		}, watcherFlags()...),
 		Action: func(ctx context.Context, c *cli.Command) error {
 			logger := log.New(log.Options{
 				Writer:        os.Stderr,
@@ -91,29 +212,68 @@ func main() {
 				ShowDebugLogs: c.Bool("debug"),
 			})
 
-			if c.NArg() == 0 {
-				return c.Command("help").Action(ctx, c)
+			shell := strings.Fields(c.String("shell"))
+			if len(shell) == 0 {
+				return fmt.Errorf("--shell must not be empty")
 			}
 
-			var watchDirs, excludeDirs []string
+			var commands []func(context.Context) *exec.Cmd
+			var kinds []executor.CommandKind
+			var readyChecks []executor.ReadyCheck
+			var servicePorts [][]int
+			for _, script := range c.StringSlice("command") {
+				kind, readyCheck, ports := executor.KindTask, executor.ReadyCheck(nil), []int(nil)
+				if svc, addr, ok := parseServiceCommand(script); ok {
+					script, kind = svc, executor.KindService
+					if addr != "" {
+						readyCheck = executor.TCPReady(addr)
 
-			for _, d := range c.StringSlice("watch") {
-				if strings.HasPrefix(d, "-") {
-					// INFO: needs to be excluded
-					excludeDirs = append(excludeDirs, d[1:])
-					continue
+						// INFO: service binds the port, it is checked for readiness on
+						if _, p, err := net.SplitHostPort(addr); err == nil {
+							if port, err := parsePort(p); err == nil {
+								ports = []int{port}
+							}
+						}
+					}
 				}
-				watchDirs = append(watchDirs, d)
+
+				commands = append(commands, shellCommand(shell, script))
+				kinds = append(kinds, kind)
+				readyChecks = append(readyChecks, readyCheck)
+				servicePorts = append(servicePorts, ports)
+			}
+
+			if args := commandArgs(c); len(args) > 0 {
+				commands = append(commands, newCommand(args[0], args[1:]...))
 			}
 
-			var watchExtensions, ignoreExtensions []string
-			for _, ext := range c.StringSlice("ext") {
-				if strings.HasPrefix(ext, "-") {
-					// INFO: needs to be excluded
-					ignoreExtensions = append(ignoreExtensions, ext[1:])
-					continue
+			var procfileEntries []procfile.Entry
+			if path := c.String("procfile"); path != "" {
+				if len(commands) > 0 {
+					return fmt.Errorf("--procfile can not be used with a command")
 				}
-				watchExtensions = append(watchExtensions, ext)
+
+				if c.String("build") != "" {
+					return fmt.Errorf("--procfile can not be used with --build")
+				}
+
+				entries, err := procfile.ParseFile(path)
+				if err != nil {
+					return err
+				}
+
+				if len(entries) == 0 {
+					return fmt.Errorf("procfile (%s) has no entries", path)
+				}
+				procfileEntries = entries
+
+				if len(c.StringSlice("pattern")) > 0 {
+					return fmt.Errorf("--pattern can not be used with --procfile, use --scope instead")
+				}
+			}
+
+			if len(commands) == 0 && len(procfileEntries) == 0 {
+				return c.Command("help").Action(ctx, c)
 			}
 
 			cooldown, err := time.ParseDuration(c.String("cooldown"))
@@ -121,51 +281,250 @@ func main() {
 				panic(err)
 			}
 
-			args := watcher.WatcherArgs{
-				Logger: logger,
+			if c.Bool("pty") && c.Bool("interactive") {
+				return fmt.Errorf("--pty and --interactive can not be used together")
+			}
 
-				WatchDirs:  watchDirs,
-				IgnoreDirs: excludeDirs,
+			if c.Bool("once") && c.Bool("until-success") {
+				return fmt.Errorf("--once and --until-success can not be used together")
+			}
 
-				WatchExtensions:  watchExtensions,
-				IgnoreExtensions: ignoreExtensions,
-				CooldownDuration: &cooldown,
+			if c.Bool("no-initial-run") && (c.Bool("once") || c.Bool("until-success")) {
+				return fmt.Errorf("--no-initial-run can not be used with --once, or --until-success")
+			}
+
+			onBusy, err := executor.ParseOnBusy(c.String("on-busy"))
+			if err != nil {
+				return err
+			}
+
+			var buildCommands []executor.CommandGroup
+			if build := c.String("build"); build != "" {
+				buildCommands = append(buildCommands, executor.CommandGroup{
+					Commands: []func(context.Context) *exec.Cmd{shellCommand(shell, build)},
+				})
+			}
+
+			envFiles := c.StringSlice("env-file")
 
-				IgnoreList: c.StringSlice("ignore-list"),
+			if len(envFiles) > 0 {
+				// INFO: env files are re-read on every run, but a broken one must fail right away, on start
+				if _, err := dotenv.ParseFiles(envFiles...); err != nil {
+					return err
+				}
 			}
 
+			procAttrs, err := processAttrs(c)
+			if err != nil {
+				return err
+			}
+
+			cgroupOpts, err := cgroupOptions(c)
+			if err != nil {
+				return err
+			}
+
+			portTimeout, err := time.ParseDuration(c.String("port-timeout"))
+			if err != nil {
+				return fmt.Errorf("invalid --port-timeout: %w", err)
+			}
+
+			readyTimeout, err := time.ParseDuration(c.String("ready-timeout"))
+			if err != nil || readyTimeout <= 0 {
+				return fmt.Errorf("invalid --ready-timeout (%s), must be a positive duration", c.String("ready-timeout"))
+			}
+
+			readyTimeouts := make([]time.Duration, len(commands))
+			for i := range readyTimeouts {
+				readyTimeouts[i] = readyTimeout
+			}
+
+			patterns, commandPatterns, err := parseCommandPatterns(c.StringSlice("pattern"), len(commands))
+			if err != nil {
+				return err
+			}
+
+			var commandPorts [][]int
+			if len(procfileEntries) == 0 {
+				ports := make([]int, 0, len(c.StringSlice("port")))
+				for _, v := range c.StringSlice("port") {
+					port, err := parsePort(v)
+					if err != nil {
+						return fmt.Errorf("invalid --port (%s): %w", v, err)
+					}
+					ports = append(ports, port)
+				}
+
+				// INFO: ports are of every task, while services bind the port of their address
+				commandPorts = make([][]int, len(commands))
+				for i := range commands {
+					commandPorts[i] = ports
+					if i < len(kinds) && kinds[i] == executor.KindService {
+						commandPorts[i] = servicePorts[i]
+					}
+				}
+			}
+
+			// INFO: subreaper is linux only
+			subreaper := runtime.GOOS == "linux" && !c.Bool("no-subreaper")
+
+			var output *executor.OutputMux
+			if (c.Bool("parallel") && len(commands) > 1) || len(procfileEntries) > 0 {
+				output = executor.NewOutputMux(executor.OutputMuxArgs{
+					NoColor:    c.Bool("no-color"),
+					Timestamps: c.Bool("timestamps"),
+				})
+			}
+
+			newCmdExecutor := func(cg executor.CommandGroup) *executor.CmdExecutor {
+				return executor.NewCmdExecutor(ctx, executor.CmdExecutorArgs{
+					Logger:        logger,
+					Interactive:   c.Bool("interactive"),
+					BuildCommands: buildCommands,
+					NoInitialRun:  c.Bool("no-initial-run"),
+					OnBusy:        onBusy,
+					Output:        output,
+					EnvFiles:      envFiles,
+					ProcessAttrs:  procAttrs,
+					Cgroup:        cgroupOpts,
+					Subreaper:     subreaper,
+					PortTimeout:   portTimeout,
+					PTY:           c.Bool("pty"),
+					Commands:      []executor.CommandGroup{cg},
+					// INFO: a parallel group runs in a slot of its own, so that its commands can be restarted on their own
+					Parallel: cg.Parallel,
+				})
+			}
+
+			var cmdExecutor executor.Executor = newCmdExecutor(executor.CommandGroup{
+				Commands:        commands,
+				Kinds:           kinds,
+				ReadyChecks:     readyChecks,
+				ReadyTimeouts:   readyTimeouts,
+				Ports:           commandPorts,
+				Patterns:        patterns,
+				CommandPatterns: commandPatterns,
+				Parallel:        c.Bool("parallel"),
+			})
+
+			if len(procfileEntries) > 0 {
+				scopes, err := parseEntryValues("scope", c.StringSlice("scope"), procfileEntries)
+				if err != nil {
+					return err
+				}
+
+				dependsOn, err := parseEntryValues("depends-on", c.StringSlice("depends-on"), procfileEntries)
+				if err != nil {
+					return err
+				}
+
+				entryPorts, err := parseEntryValues("port", c.StringSlice("port"), procfileEntries)
+				if err != nil {
+					return err
+				}
+
+				ports := make(map[string][]int, len(entryPorts))
+				for name, values := range entryPorts {
+					for _, v := range values {
+						port, err := parsePort(v)
+						if err != nil {
+							return fmt.Errorf("invalid --port (%s=%s): %w", name, v, err)
+						}
+						ports[name] = append(ports[name], port)
+					}
+				}
+
+				entryCommands := func(entry procfile.Entry) executor.CommandGroup {
+					return executor.CommandGroup{
+						Commands: []func(context.Context) *exec.Cmd{shellCommand(shell, entry.Command)},
+						Names:    []string{entry.Name},
+						Ports:    [][]int{ports[entry.Name]},
+					}
+				}
+
+				switch {
+				case len(dependsOn) > 0:
+					if c.IsSet("on-busy") {
+						return fmt.Errorf("--on-busy can not be used with --depends-on")
+					}
+
+					nodes := make([]executor.DAGNode, 0, len(procfileEntries))
+					for _, entry := range procfileEntries {
+						nodes = append(nodes, executor.DAGNode{
+							Name:      entry.Name,
+							Commands:  []executor.CommandGroup{entryCommands(entry)},
+							DependsOn: dependsOn[entry.Name],
+							Dirs:      scopes[entry.Name],
+						})
+					}
+
+					cmdExecutor, err = executor.NewDAGExecutor(ctx, executor.DAGExecutorArgs{
+						Logger:       logger,
+						Nodes:        nodes,
+						Interactive:  c.Bool("interactive"),
+						NoInitialRun: c.Bool("no-initial-run"),
+						Output:       output,
+						EnvFiles:     envFiles,
+						ProcessAttrs: procAttrs,
+						Cgroup:       cgroupOpts,
+						Subreaper:    subreaper,
+						PortTimeout:  portTimeout,
+						PTY:          c.Bool("pty"),
+					})
+					if err != nil {
+						return err
+					}
+				default:
+					// INFO: every entry has its own executor, so that it can be restarted on its own
+					entryExecutors := make([]executor.Executor, 0, len(procfileEntries))
+					for _, entry := range procfileEntries {
+						dirs := scopes[entry.Name]
+						if len(dirs) > 0 {
+							// INFO: a scoped entry, must still restart as env files change
+							dirs = append(dirs, envFiles...)
+						}
+						entryExecutors = append(entryExecutors, executor.NewScopedExecutor(newCmdExecutor(entryCommands(entry)), dirs...))
+					}
+					cmdExecutor = executor.NewParallelExecutor(entryExecutors...)
+				}
+			}
+
+			if c.Bool("once") {
+				go func() {
+					<-ctx.Done()
+					cmdExecutor.Stop()
+				}()
+				err := cmdExecutor.Start()
+				if ctx.Err() != nil {
+					return exitWith(ctx.Err())
+				}
+				return exitWith(err)
+			}
+
+			args := watcherArgs(c)
+			args.Logger = logger
+			args.CooldownDuration = &cooldown
+			// INFO: large trees take a while to be walked, and command need not wait for it
+			args.WalkInBackground = true
+			// INFO: env files are watched, even if they are not in the watch list, or are filtered out by extensions
+			args.WatchDirs = append(args.WatchDirs, envFiles...)
+
 			w, err := watcher.NewWatcher(ctx, args)
 			if err != nil {
 				panic(err)
 			}
 
+			if c.Bool("until-success") {
+				return exitWith(w.ExecuteUntilSuccess(ctx, cmdExecutor))
+			}
+
 			var executors []executor.Executor
 
 			if sseAddr := c.String("sse-addr"); sseAddr != "" {
 				executors = append(executors, executor.NewSSEExecutor(executor.SSEExecutorArgs{Addr: sseAddr}))
 			}
 
-			if c.NArg() > 0 {
-				execCmd := c.Args().First()
-				execArgs := c.Args().Tail()
-				executors = append(executors, executor.NewCmdExecutor(ctx, executor.CmdExecutorArgs{
-					Logger:      logger,
-					Interactive: c.Bool("interactive"),
-					Commands: []executor.CommandGroup{
-						{
-							Commands: []func(context.Context) *exec.Cmd{
-								func(c context.Context) *exec.Cmd {
-									cmd := exec.CommandContext(ctx, execCmd, execArgs...)
-									cmd.Stdout = os.Stdout
-									cmd.Stderr = os.Stderr
-									cmd.Stdin = os.Stdin
-									return cmd
-								},
-							},
-						},
-					},
-				}))
-			}
+			executors = append(executors, cmdExecutor)
 
 			if err := w.WatchAndExecute(ctx, executors); err != nil {
 				return err
@@ -183,3 +542,165 @@ func main() {
 	}
 	os.Exit(0)
 }
+
+// commandArgs is the command to run, as passed in args, i.e. after flags
+func commandArgs(c *cli.Command) []string {
+	args := c.Args().Slice()
+	// INFO: flags parsing stops at "--", but it is kept in args
+	if len(args) > 0 && args[0] == "--" {
+		args = args[1:]
+	}
+	return args
+}
+
+func newCommand(name string, args ...string) func(context.Context) *exec.Cmd {
+	return func(ctx context.Context) *exec.Cmd {
+		cmd := exec.CommandContext(ctx, name, args...)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		cmd.Stdin = os.Stdin
+		return cmd
+	}
+}
+
+// shellCommand runs script with shell, as <shell> -c <script>
+func shellCommand(shell []string, script string) func(context.Context) *exec.Cmd {
+	args := append(append([]string{}, shell[1:]...), "-c", script)
+	return newCommand(shell[0], args...)
+}
+
+// processAttrs builds process attributes of commands, from --dir, --user, --umask and --limit flags
+func processAttrs(c *cli.Command) (executor.ProcessAttrs, error) {
+	attrs := executor.ProcessAttrs{Dir: c.String("dir")}
+	attrs.User, attrs.Group, _ = strings.Cut(c.String("user"), ":")
+
+	if s := c.String("umask"); s != "" {
+		umask, err := strconv.ParseUint(s, 8, 32)
+		if err != nil || umask > 0o777 {
+			return attrs, fmt.Errorf("invalid --umask (%s), must be octal, like 027", s)
+		}
+		v := int(umask)
+		attrs.Umask = &v
+	}
+
+	for _, limit := range c.StringSlice("limit") {
+		if err := executor.ParseLimit(limit, &attrs.Limits); err != nil {
+			return attrs, fmt.Errorf("invalid --limit: %w", err)
+		}
+	}
+
+	return attrs, nil
+}
+
+// cgroupOptions builds cgroup options of commands, from --no-cgroup, --memory-max and --cpu-max flags
+func cgroupOptions(c *cli.Command) (executor.CgroupOptions, error) {
+	// INFO: cgroups are opt-in for executors, while fwatcher enables them by default
+	opts := executor.CgroupOptions{Enable: !c.Bool("no-cgroup"), CPUMax: c.Float("cpu-max")}
+
+	if s := c.String("memory-max"); s != "" {
+		n, err := executor.ParseSize(s)
+		if err != nil {
+			return opts, fmt.Errorf("invalid --memory-max (%s): %w", s, err)
+		}
+		opts.MemoryMax = n
+	}
+
+	if opts.CPUMax < 0 {
+		return opts, fmt.Errorf("invalid --cpu-max (%v), must be positive", opts.CPUMax)
+	}
+
+	if !opts.Enable && (opts.MemoryMax > 0 || opts.CPUMax > 0) {
+		return opts, fmt.Errorf("--memory-max, and --cpu-max can not be used with --no-cgroup")
+	}
+
+	return opts, nil
+}
+
+// parsePort parses a tcp port
+func parsePort(s string) (int, error) {
+	port, err := strconv.Atoi(s)
+	if err != nil || port < 1 || port > 65535 {
+		return 0, fmt.Errorf("must be a port number, between 1 and 65535")
+	}
+	return port, nil
+}
+
+// exitWith makes fwatcher exit with the exit code of command, that failed with err
+func exitWith(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, context.Canceled) {
+		return cli.Exit("", 130)
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		code := exitErr.ExitCode()
+		if code < 0 {
+			// INFO: process got killed by a signal
+			code = 1
+		}
+		return cli.Exit("", code)
+	}
+
+	return err
+}
+
+var serviceRegex = regexp.MustCompile(`^service(?:\(([^)]+)\))?:\s*(.+)$`)
+
+// parseServiceCommand parses commands of form `service: <command>`, or `service(<addr>): <command>`, i.e. services that
+// are ready once started, or once addr accepts tcp connections
+func parseServiceCommand(s string) (command string, addr string, ok bool) {
+	m := serviceRegex.FindStringSubmatch(s)
+	if m == nil {
+		return "", "", false
+	}
+	return m[2], m[1], true
+}
+
+// parseCommandPatterns parses values of --pattern, of form <pattern>, or <n>=<pattern>, into patterns of all the commands,
+// and patterns of n-th command (counting from 1), among n commands
+func parseCommandPatterns(values []string, n int) (patterns []string, commandPatterns [][]string, err error) {
+	for _, v := range values {
+		idx, pattern, ok := strings.Cut(v, "=")
+		i, err := strconv.Atoi(idx)
+		if !ok || err != nil {
+			patterns = append(patterns, v)
+			continue
+		}
+
+		if i < 1 || i > n || pattern == "" {
+			return nil, nil, fmt.Errorf("invalid --pattern (%s), must be of form <pattern>, or <n>=<pattern> with 1 <= n <= %d", v, n)
+		}
+
+		if commandPatterns == nil {
+			commandPatterns = make([][]string, n)
+		}
+		commandPatterns[i-1] = append(commandPatterns[i-1], pattern)
+	}
+	return patterns, commandPatterns, nil
+}
+
+// parseEntryValues parses values of flag, of form <entry>=<value>, into values by procfile entry
+func parseEntryValues(flag string, values []string, entries []procfile.Entry) (map[string][]string, error) {
+	known := make(map[string]struct{}, len(entries))
+	for _, entry := range entries {
+		known[entry.Name] = struct{}{}
+	}
+
+	result := make(map[string][]string, len(values))
+	for _, v := range values {
+		name, value, ok := strings.Cut(v, "=")
+		if !ok || name == "" || value == "" {
+			return nil, fmt.Errorf("invalid --%s (%s), must be of form <entry>=<value>", flag, v)
+		}
+
+		if _, ok := known[name]; !ok {
+			return nil, fmt.Errorf("invalid --%s (%s), procfile has no entry (%s)", flag, v, name)
+		}
+		result[name] = append(result[name], value)
+	}
+	return result, nil
+}
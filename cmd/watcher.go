@@ -0,0 +1,95 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/nxtcoder17/fwatcher/pkg/watcher"
+	"github.com/urfave/cli/v3"
+)
+
+// watcherFlags are flags that decide what is to be watched, they are shared by every command that needs a watcher
+func watcherFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringSliceFlag{
+			Name:    "watch",
+			Usage:   "[dir|file][:depth=N|:non-recursive] (to watch) | -[dir] (to ignore)",
+			Value:   []string{"."},
+			Aliases: []string{"w"},
+		},
+
+		&cli.StringSliceFlag{
+			Name:     "ext",
+			Usage:    "[ext] (to watch) | -[ext] (to ignore)",
+			Required: false,
+			Aliases:  []string{"e"},
+		},
+
+		// &cli.StringSliceFlag{
+		// 	Name:    "exclude",
+		// 	Usage:   "exclude this directory",
+		// 	Aliases: []string{"x"},
+		// },
+
+		&cli.StringSliceFlag{
+			Name:    "ignore-list",
+			Usage:   "disables ignoring from default ignore list",
+			Value:   watcher.DefaultIgnoreList,
+			Aliases: []string{"I"},
+		},
+
+		&cli.StringFlag{
+			Name:  "go",
+			Usage: "[package] (e.g. ./cmd/api) watch only those directories and files, that the go package depends upon",
+		},
+
+		&cli.BoolFlag{
+			Name:    "follow-symlinks",
+			Usage:   "watch symlinked directories, by following them to their targets",
+			Aliases: []string{"L"},
+		},
+	}
+}
+
+// watcherArgs builds watcher args, from flags defined in watcherFlags
+func watcherArgs(c *cli.Command) watcher.WatcherArgs {
+	var watchDirs, excludeDirs []string
+
+	watch := c.StringSlice("watch")
+	if c.String("go") != "" && !c.IsSet("watch") {
+		// INFO: in go mode, go package decides what to watch, instead of the default watch dir
+		watch = nil
+	}
+
+	for _, d := range watch {
+		if strings.HasPrefix(d, "-") {
+			// INFO: needs to be excluded
+			excludeDirs = append(excludeDirs, d[1:])
+			continue
+		}
+		watchDirs = append(watchDirs, d)
+	}
+
+	var watchExtensions, ignoreExtensions []string
+	for _, ext := range c.StringSlice("ext") {
+		if strings.HasPrefix(ext, "-") {
+			// INFO: needs to be excluded
+			ignoreExtensions = append(ignoreExtensions, ext[1:])
+			continue
+		}
+		watchExtensions = append(watchExtensions, ext)
+	}
+
+	return watcher.WatcherArgs{
+		WatchDirs:  watchDirs,
+		IgnoreDirs: excludeDirs,
+
+		WatchExtensions:  watchExtensions,
+		IgnoreExtensions: ignoreExtensions,
+
+		IgnoreList: c.StringSlice("ignore-list"),
+
+		FollowSymlinks: c.Bool("follow-symlinks"),
+
+		GoPackage: c.String("go"),
+	}
+}
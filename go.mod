@@ -3,14 +3,15 @@ module github.com/nxtcoder17/fwatcher
 go 1.22.10
 
 require (
+	github.com/creack/pty v1.1.24
 	github.com/fsnotify/fsnotify v1.6.0
 	github.com/nxtcoder17/go.pkgs v0.0.0-20250126144455-1acf7c99bcd9
 	github.com/urfave/cli/v3 v3.0.0-beta1
+	golang.org/x/sys v0.19.0
 )
 
 require (
 	github.com/mattn/go-colorable v0.1.13 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
 	github.com/rs/zerolog v1.33.0 // indirect
-	golang.org/x/sys v0.19.0 // indirect
 )
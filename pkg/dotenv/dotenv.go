@@ -0,0 +1,144 @@
+// Package dotenv parses env files, i.e. files with lines like `KEY=value`
+package dotenv
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+var keyRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+
+// Parse parses env file from r. It supports:
+//   - comments (# ...), empty lines, and `export` prefixes
+//   - single quoted values, taken as is
+//   - double quoted values, with \n, \t, \", \\ escapes, and ${VAR} expansion
+//   - unquoted values, with inline comments (after ` #`), and ${VAR} expansion
+//
+// ${VAR} expands to a value defined earlier in the file, or in lookup (if not nil)
+func Parse(r io.Reader, lookup func(key string) (string, bool)) (map[string]string, error) {
+	env := map[string]string{}
+
+	expand := func(s string) string {
+		return os.Expand(s, func(key string) string {
+			if v, ok := env[key]; ok {
+				return v
+			}
+			if lookup != nil {
+				if v, ok := lookup(key); ok {
+					return v
+				}
+			}
+			return ""
+		})
+	}
+
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		line = strings.TrimPrefix(line, "export ")
+
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || !keyRegex.MatchString(key) {
+			return nil, fmt.Errorf("line %d: invalid entry (%s), must be of form KEY=value", lineNo, line)
+		}
+
+		value = strings.TrimSpace(value)
+		switch {
+		case strings.HasPrefix(value, "'"):
+			end := strings.Index(value[1:], "'")
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated single quoted value", lineNo)
+			}
+			value = value[1 : end+1]
+		case strings.HasPrefix(value, `"`):
+			v, err := unquote(value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			value = expand(v)
+		default:
+			if i := strings.Index(value, " #"); i >= 0 {
+				value = strings.TrimSpace(value[:i])
+			}
+			value = expand(value)
+		}
+
+		env[key] = value
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return env, nil
+}
+
+// unquote unquotes a double quoted value, ignoring anything after its closing quote
+func unquote(value string) (string, error) {
+	var sb strings.Builder
+	for i := 1; i < len(value); i++ {
+		switch value[i] {
+		case '"':
+			return sb.String(), nil
+		case '\\':
+			if i+1 < len(value) {
+				i++
+				switch value[i] {
+				case 'n':
+					sb.WriteByte('\n')
+				case 't':
+					sb.WriteByte('\t')
+				case 'r':
+					sb.WriteByte('\r')
+				default:
+					sb.WriteByte(value[i])
+				}
+				continue
+			}
+			sb.WriteByte('\\')
+		default:
+			sb.WriteByte(value[i])
+		}
+	}
+	return "", fmt.Errorf("unterminated double quoted value")
+}
+
+// ParseFiles parses env files at paths, in order, so that values from a later file override earlier ones.
+// ${VAR} in a file, can refer to values from earlier files, or from the environment
+func ParseFiles(paths ...string) (map[string]string, error) {
+	env := map[string]string{}
+	lookup := func(key string) (string, bool) {
+		if v, ok := env[key]; ok {
+			return v, true
+		}
+		return os.LookupEnv(key)
+	}
+
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+
+		values, err := Parse(f, lookup)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("env file (%s): %w", path, err)
+		}
+
+		for k, v := range values {
+			env[k] = v
+		}
+	}
+
+	return env, nil
+}
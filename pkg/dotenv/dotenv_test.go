@@ -0,0 +1,97 @@
+package dotenv
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_Parse(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		lookup  map[string]string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name:  "1. simple values, comments, and export",
+			input: "# db\nDB_HOST=localhost\n\nexport DB_PORT = 5432\n",
+			want:  map[string]string{"DB_HOST": "localhost", "DB_PORT": "5432"},
+		},
+		{
+			name:  "2. quoted values",
+			input: "A='raw ${B} \\n'\nB=\"line\\nnext \\\"quoted\\\"\" # comment\nC=\"\"",
+			want:  map[string]string{"A": "raw ${B} \\n", "B": "line\nnext \"quoted\"", "C": ""},
+		},
+		{
+			name:  "3. inline comments in unquoted values",
+			input: "A=value # comment\nB=value#not-a-comment",
+			want:  map[string]string{"A": "value", "B": "value#not-a-comment"},
+		},
+		{
+			name:   "4. expansion",
+			input:  "HOST=localhost\nURL=http://${HOST}:${PORT}/$NAME\n",
+			lookup: map[string]string{"PORT": "8080"},
+			want:   map[string]string{"HOST": "localhost", "URL": "http://localhost:8080/"},
+		},
+		{
+			name:    "5. invalid key",
+			input:   "1KEY=value",
+			wantErr: true,
+		},
+		{
+			name:    "6. missing =",
+			input:   "KEY",
+			wantErr: true,
+		},
+		{
+			name:    "7. unterminated quote",
+			input:   "KEY=\"value",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lookup := func(key string) (string, bool) {
+				v, ok := tt.lookup[key]
+				return v, ok
+			}
+
+			got, err := Parse(strings.NewReader(tt.input), lookup)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FAILED (%s)\n\t got err: %v\n\twant err: %v\n", tt.name, err, tt.wantErr)
+			}
+
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FAILED (%s)\n\t got: %v\n\twant: %v\n", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_ParseFiles(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, ".env")
+	local := filepath.Join(dir, ".env.local")
+
+	if err := os.WriteFile(base, []byte("HOST=localhost\nPORT=80\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(local, []byte("PORT=8080\nURL=${HOST}:${PORT}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ParseFiles(base, local)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := map[string]string{"HOST": "localhost", "PORT": "8080", "URL": "localhost:8080"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("FAILED\n\t got: %v\n\twant: %v\n", got, want)
+	}
+}
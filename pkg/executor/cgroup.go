@@ -0,0 +1,41 @@
+package executor
+
+import "fmt"
+
+// CgroupOptions configure transient cgroups (v2), that commands are placed in, on linux. With a cgroup, every descendant
+// of a command gets killed on restart, even the ones that escaped its process group (via setsid, or by daemonizing).
+// Commands are placed in cgroups only when enabled, and if cgroup v2 is mounted, and the current process's own cgroup is
+// writable.
+//
+// NOTE: to enable the memory controller (for peak memory, and memory limits), and the cpu controller (for cpu limits),
+// the current process moves itself into a leaf cgroup (fwatcher-<pid>) of its own cgroup, as cgroup v2 allows
+// controllers for child cgroups, only in cgroups without processes.
+type CgroupOptions struct {
+	// Enable places commands in cgroups
+	Enable bool
+
+	// MemoryMax (if not zero) is memory.max of a command's cgroup, in bytes, i.e. command gets OOM killed beyond it
+	MemoryMax uint64
+
+	// CPUMax (if not zero) limits cpu usage of a command, to these many cpus (like 1.5), i.e. cpu.max
+	CPUMax float64
+}
+
+func (opts CgroupOptions) hasLimits() bool {
+	return opts.MemoryMax > 0 || opts.CPUMax > 0
+}
+
+// formatBytes formats n bytes, like 12.5MiB
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,297 @@
+package executor
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+/*
+Every command gets its own cgroup, as a child of fwatcher's own cgroup, like <fwatcher's cgroup>/fwatcher-<pid>-<n>.
+Processes are born in their cgroup (via CLONE_INTO_CGROUP), so that nothing escapes it, before it is placed in there.
+
+cgroup v2 allows controllers (memory, cpu) to be enabled for children, only for a cgroup without processes. So, fwatcher
+moves itself into a leaf (fwatcher-<pid>) of its own cgroup, for memory controller (peak memory of every run, and memory
+limits), and cpu controller (cpu limits). It works, when it has its own cgroup, like with
+`systemd-run --user --scope -p Delegate=yes fwatcher ...`, otherwise it moves back, and runs commands without controllers.
+*/
+
+// cgroupBase is the cgroup v2 directory of fwatcher (as it started), it is empty if cgroups are unavailable
+var cgroupBase = sync.OnceValue(func() string {
+	base, err := findCgroupBase()
+	if err != nil {
+		return ""
+	}
+
+	// INFO: CLONE_INTO_CGROUP needs linux 5.7+
+	if !kernelAtLeast(5, 7) {
+		return ""
+	}
+
+	if err := unix.Access(base, unix.W_OK); err != nil {
+		return ""
+	}
+	return base
+})
+
+// cgroupControllers enables memory controller (and, cpu controller, if it is delegated too) for children of cgroupBase,
+// moving the current process into a leaf. It tells whether cpu controller got enabled
+var cgroupControllers = sync.OnceValues(func() (bool, error) {
+	base := cgroupBase()
+
+	leaf := filepath.Join(base, fmt.Sprintf("fwatcher-%d", os.Getpid()))
+	if err := os.Mkdir(leaf, 0o755); err != nil && !errors.Is(err, os.ErrExist) {
+		return false, err
+	}
+
+	if err := os.WriteFile(filepath.Join(leaf, "cgroup.procs"), []byte(strconv.Itoa(os.Getpid())), 0o644); err != nil {
+		os.Remove(leaf)
+		return false, err
+	}
+
+	if err := os.WriteFile(filepath.Join(base, "cgroup.subtree_control"), []byte("+memory"), 0o644); err != nil {
+		// INFO: moving back, as fwatcher is not the only process in its cgroup
+		os.WriteFile(filepath.Join(base, "cgroup.procs"), []byte(strconv.Itoa(os.Getpid())), 0o644)
+		os.Remove(leaf)
+		return false, fmt.Errorf("failed to enable memory controller in cgroup (%s), run fwatcher in a cgroup of its own, with controllers delegated to it: %w", base, err)
+	}
+
+	err := os.WriteFile(filepath.Join(base, "cgroup.subtree_control"), []byte("+cpu"), 0o644)
+	return err == nil, nil
+})
+
+// findCgroupBase finds cgroup v2 directory of the current process, from its mount, and /proc/self/cgroup
+func findCgroupBase() (string, error) {
+	b, err := os.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return "", err
+	}
+
+	var cgroupPath string
+	for _, line := range strings.Split(string(b), "\n") {
+		if p, ok := strings.CutPrefix(line, "0::"); ok {
+			cgroupPath = p
+			break
+		}
+	}
+	if cgroupPath == "" {
+		return "", fmt.Errorf("process is not in a cgroup v2 hierarchy")
+	}
+
+	f, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// INFO: mountinfo line looks like, 42 32 0:38 <root> <mount point> <options> ... - cgroup2 cgroup2 rw
+		fields := strings.Fields(scanner.Text())
+		sep := -1
+		for i, field := range fields {
+			if field == "-" {
+				sep = i
+				break
+			}
+		}
+		if sep < 5 || sep+1 >= len(fields) || fields[sep+1] != "cgroup2" {
+			continue
+		}
+
+		root, mountPoint := fields[3], fields[4]
+		rel, err := filepath.Rel(root, cgroupPath)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		return filepath.Join(mountPoint, rel), nil
+	}
+
+	return "", fmt.Errorf("cgroup v2 is not mounted")
+}
+
+func kernelAtLeast(major, minor int) bool {
+	var uts unix.Utsname
+	if err := unix.Uname(&uts); err != nil {
+		return false
+	}
+
+	release := string(bytes.TrimRight(uts.Release[:], "\x00"))
+	parts := strings.SplitN(release, ".", 3)
+	if len(parts) < 2 {
+		return false
+	}
+
+	maj, _ := strconv.Atoi(parts[0])
+	min, _ := strconv.Atoi(strings.TrimFunc(parts[1], func(r rune) bool { return r < '0' || r > '9' }))
+	return maj > major || (maj == major && min >= minor)
+}
+
+// cgroupManager creates cgroups for commands of an executor
+type cgroupManager struct {
+	opts   CgroupOptions
+	logger *slog.Logger
+	// memory, and cpu tell whether those controllers are enabled, i.e. whether peak memory gets reported, and whether
+	// memory, and cpu limits can be enforced
+	memory bool
+	cpu    bool
+}
+
+var cgroupSeq atomic.Int64
+
+// newCgroupManager returns nil, if commands can not be placed in cgroups
+func newCgroupManager(opts CgroupOptions, logger *slog.Logger) *cgroupManager {
+	if !opts.Enable || cgroupBase() == "" {
+		if opts.hasLimits() {
+			logger.Warn("cgroup v2 is not available (or, is not writable), memory, and cpu limits will not be enforced")
+		}
+		return nil
+	}
+
+	m := &cgroupManager{opts: opts, logger: logger}
+
+	cpu, err := cgroupControllers()
+	if err != nil {
+		if opts.hasLimits() {
+			logger.Warn("memory, and cpu limits will not be enforced", "err", err)
+		} else {
+			logger.Debug("peak memory of commands will not be reported", "err", err)
+		}
+		return m
+	}
+
+	m.memory, m.cpu = true, cpu
+	if opts.CPUMax > 0 && !cpu {
+		logger.Warn("cpu controller is not delegated to fwatcher's cgroup, cpu limit will not be enforced")
+	}
+	return m
+}
+
+// cgroup is a transient cgroup of a single command
+type cgroup struct {
+	path string
+	fd   *os.File
+}
+
+// create creates a new cgroup, with limits (if they can be enforced)
+func (m *cgroupManager) create() (*cgroup, error) {
+	path := filepath.Join(cgroupBase(), fmt.Sprintf("fwatcher-%d-%d", os.Getpid(), cgroupSeq.Add(1)))
+	if err := os.Mkdir(path, 0o755); err != nil {
+		return nil, err
+	}
+
+	cg := &cgroup{path: path}
+
+	if m.memory && m.opts.MemoryMax > 0 {
+		if err := cg.write("memory.max", strconv.FormatUint(m.opts.MemoryMax, 10)); err != nil {
+			cg.remove()
+			return nil, err
+		}
+	}
+
+	if m.cpu && m.opts.CPUMax > 0 {
+		const period = 100000
+		if err := cg.write("cpu.max", fmt.Sprintf("%d %d", int(m.opts.CPUMax*period), period)); err != nil {
+			cg.remove()
+			return nil, err
+		}
+	}
+
+	fd, err := os.Open(path)
+	if err != nil {
+		cg.remove()
+		return nil, err
+	}
+	cg.fd = fd
+
+	return cg, nil
+}
+
+func (cg *cgroup) write(file string, value string) error {
+	return os.WriteFile(filepath.Join(cg.path, file), []byte(value), 0o644)
+}
+
+// attach makes cmd's process, be born in cg
+func (cg *cgroup) attach(cmd *exec.Cmd) {
+	cmd.SysProcAttr.UseCgroupFD = true
+	cmd.SysProcAttr.CgroupFD = int(cg.fd.Fd())
+}
+
+// started closes cgroup's fd, that is needed only until process starts
+func (cg *cgroup) started() {
+	cg.fd.Close()
+}
+
+// populated tells whether cg still has processes
+func (cg *cgroup) populated() bool {
+	b, err := os.ReadFile(filepath.Join(cg.path, "cgroup.events"))
+	if err != nil {
+		return false
+	}
+	return bytes.Contains(b, []byte("populated 1"))
+}
+
+// kill kills every process in cg
+func (cg *cgroup) kill() error {
+	// INFO: cgroup.kill needs linux 5.14+, otherwise processes are killed one by one, until none is left
+	if err := cg.write("cgroup.kill", "1"); err == nil {
+		return nil
+	}
+
+	for i := 0; i < 100 && cg.populated(); i++ {
+		b, err := os.ReadFile(filepath.Join(cg.path, "cgroup.procs"))
+		if err != nil {
+			return err
+		}
+
+		for _, line := range strings.Fields(string(b)) {
+			if pid, err := strconv.Atoi(line); err == nil {
+				syscall.Kill(pid, syscall.SIGKILL)
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return nil
+}
+
+// peakMemory is the peak memory usage of cg, it needs the memory controller, and linux 5.19+
+func (cg *cgroup) peakMemory() (uint64, bool) {
+	b, err := os.ReadFile(filepath.Join(cg.path, "memory.peak"))
+	if err != nil {
+		return 0, false
+	}
+
+	n, err := strconv.ParseUint(strings.TrimSpace(string(b)), 10, 64)
+	return n, err == nil
+}
+
+// remove kills every process in cg, and removes it, once they have exited
+func (cg *cgroup) remove() error {
+	if cg.populated() {
+		if err := cg.kill(); err != nil {
+			return err
+		}
+	}
+
+	var err error
+	for i := 0; i < 500; i++ {
+		if err = os.Remove(cg.path); err == nil || errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return err
+}
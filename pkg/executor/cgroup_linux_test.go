@@ -0,0 +1,166 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nxtcoder17/go.pkgs/log"
+)
+
+// alive tells whether process pid is running, i.e. exists, and is not a zombie
+func alive(pid int) bool {
+	b, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return false
+	}
+	// INFO: stat looks like, <pid> (<comm>) <state> ...
+	fields := strings.Fields(string(b[strings.LastIndexByte(string(b), ')')+1:]))
+	return len(fields) > 0 && fields[0] != "Z"
+}
+
+// waitForPID waits for a pid to be written to file
+func waitForPID(t *testing.T, file string) int {
+	for i := 0; i < 100; i++ {
+		b, err := os.ReadFile(file)
+		if pid, err2 := strconv.Atoi(strings.TrimSpace(string(b))); err == nil && err2 == nil {
+			return pid
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("no pid got written to %s", file)
+	return 0
+}
+
+func Test_Executor_Cgroup(t *testing.T) {
+	if cgroupBase() == "" {
+		t.Skip("cgroup v2 is not available, or is not writable")
+	}
+
+	tests := []struct {
+		name string
+		// script starts a process, that escapes its process group, and writes its pid to $PID_FILE
+		script string
+	}{
+		{
+			name:   "1. escaped process of a running command",
+			script: `setsid sh -c 'echo $$ > $PID_FILE; exec sleep 100' & sleep 100`,
+		},
+		{
+			name:   "2. escaped process of an exited command",
+			script: `setsid sh -c 'echo $$ > $PID_FILE; exec sleep 100' &`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pidFile := filepath.Join(t.TempDir(), "pid")
+
+			ex := NewCmdExecutor(context.TODO(), CmdExecutorArgs{
+				Logger: log.New(log.Options{ShowDebugLogs: os.Getenv("DEBUG") == "true"}),
+				Cgroup: CgroupOptions{Enable: true},
+				Commands: []CommandGroup{
+					{
+						Commands: []func(c context.Context) *exec.Cmd{
+							func(c context.Context) *exec.Cmd {
+								cmd := exec.CommandContext(c, "sh", "-c", tt.script)
+								cmd.Env = append(os.Environ(), "PID_FILE="+pidFile)
+								return cmd
+							},
+						},
+					},
+				},
+			})
+
+			go ex.Start()
+			defer ex.Stop()
+
+			pid := waitForPID(t, pidFile)
+			if !alive(pid) {
+				t.Fatalf("FAILED (%s), escaped process (%d) is not running", tt.name, pid)
+			}
+
+			os.Remove(pidFile)
+			ex.OnWatchEvent(Event{Source: "main.go"})
+			waitForPID(t, pidFile)
+
+			if alive(pid) {
+				t.Errorf("FAILED (%s), escaped process (%d) is still running after restart", tt.name, pid)
+			}
+		})
+	}
+}
+
+func Test_Executor_Cgroup_OptIn(t *testing.T) {
+	if cgroupBase() == "" {
+		t.Skip("cgroup v2 is not available, or is not writable")
+	}
+
+	// ownCgroup is the cgroup v2 path, of the current process
+	ownCgroup := func() string {
+		b, err := os.ReadFile("/proc/self/cgroup")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, line := range strings.Split(string(b), "\n") {
+			if p, ok := strings.CutPrefix(line, "0::"); ok {
+				return p
+			}
+		}
+		return ""
+	}
+
+	tests := []struct {
+		name    string
+		cgroup  CgroupOptions
+		inOwnCg bool
+	}{
+		{
+			name:    "1. without enable, command runs in cgroup of the current process",
+			cgroup:  CgroupOptions{},
+			inOwnCg: true,
+		},
+		{
+			name:    "2. with enable, command runs in a cgroup of its own",
+			cgroup:  CgroupOptions{Enable: true},
+			inOwnCg: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			own := ownCgroup()
+
+			b := new(bytes.Buffer)
+			w := Writer{b: b, m: sync.Mutex{}}
+
+			ex := NewCmdExecutor(context.TODO(), CmdExecutorArgs{
+				Logger: log.New(log.Options{ShowDebugLogs: os.Getenv("DEBUG") == "true"}),
+				Cgroup: tt.cgroup,
+				Commands: []CommandGroup{
+					{Commands: []func(c context.Context) *exec.Cmd{shCmd(&w, "sed -n 's/^0:://p' /proc/self/cgroup")}},
+				},
+			})
+
+			if err := ex.Start(); err != nil {
+				t.Fatal(err)
+			}
+
+			w.m.Lock()
+			got := strings.TrimSpace(b.String())
+			w.m.Unlock()
+
+			if (got == own) != tt.inOwnCg {
+				t.Errorf("FAILED (%s)\n\t got: %s\n\t own: %s\n", tt.name, got, own)
+			}
+		})
+	}
+}
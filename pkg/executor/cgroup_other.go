@@ -0,0 +1,34 @@
+//go:build !linux
+
+package executor
+
+import (
+	"log/slog"
+	"os/exec"
+)
+
+// cgroupManager creates cgroups for commands, cgroups exist only on linux
+type cgroupManager struct{}
+
+type cgroup struct{}
+
+func newCgroupManager(opts CgroupOptions, logger *slog.Logger) *cgroupManager {
+	if opts.hasLimits() {
+		logger.Warn("cgroups are only supported on linux, memory, and cpu limits will not be enforced")
+	}
+	return nil
+}
+
+func (m *cgroupManager) create() (*cgroup, error) { return &cgroup{}, nil }
+
+func (cg *cgroup) attach(cmd *exec.Cmd) {}
+
+func (cg *cgroup) started() {}
+
+func (cg *cgroup) populated() bool { return false }
+
+func (cg *cgroup) kill() error { return nil }
+
+func (cg *cgroup) peakMemory() (uint64, bool) { return 0, false }
+
+func (cg *cgroup) remove() error { return nil }
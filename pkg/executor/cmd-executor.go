@@ -2,40 +2,172 @@ package executor
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
 	"os"
 	"os/exec"
+	"slices"
 	"strings"
 	"sync"
 	"syscall"
+	"time"
 )
 
 type CommandGroup struct {
-	Groups           []CommandGroup
-	Commands         []func(context.Context) *exec.Cmd
+	Groups   []CommandGroup
+	Commands []func(context.Context) *exec.Cmd
+	// Names (optional) of Commands, by index, they prefix output of commands, when it is multiplexed
+	Names []string
+	// Kinds (optional) of Commands, by index, they default to KindTask. They matter only in a sequential group
+	Kinds []CommandKind
+	// ReadyChecks (optional) of Commands of KindService, by index. A service without one, is ready once it has started
+	ReadyChecks []ReadyCheck
+	// ReadyTimeouts (optional) of Commands of KindService, by index, they default to DefaultReadyTimeout. A service that
+	// is not ready in time, is stopped, and fails with ErrServiceNotReady
+	ReadyTimeouts []time.Duration
+	// Envs (optional) of Commands, by index, they override env of the process, and values from env files
+	Envs []map[string]string
+	// Ports (optional) of Commands, by index, are the tcp ports they bind. A command is started, only once they are free,
+	// i.e. after its previous instance has released them
+	Ports [][]int
+	// ProcessAttrs (optional) of Commands, override (non-zero) process attributes of the executor
+	ProcessAttrs *ProcessAttrs
+	// CommandPatterns (optional) of Commands, by index, restrict restarts of a command (along with Patterns of the group),
+	// to changes matching them. Commands of a parallel group are restarted on their own (unless Patterns restrict its
+	// Groups too), while commands of a sequential group restart it as a whole
+	CommandPatterns [][]string
+	// Patterns (optional) restrict restarts of this group, to changes matching them (see matchPattern).
+	// Only groups running in parallel with their siblings, can be restarted on their own, others restart with their parent
+	Patterns         []string
 	PreExecCommand   func(cmd *exec.Cmd)
 	PostExecCommmand func(cmd *exec.Cmd)
 	Parallel         bool
 }
 
+// OnBusy tells what CmdExecutor does with a watch event, that arrives while commands are still running
+type OnBusy string
+
+const (
+	// OnBusyRestart cancels running commands, and starts them again
+	OnBusyRestart OnBusy = "restart"
+	// OnBusyQueue lets running commands finish, and then runs them once more
+	OnBusyQueue OnBusy = "queue"
+	// OnBusyIgnore drops the event
+	OnBusyIgnore OnBusy = "ignore"
+)
+
+// ParseOnBusy parses s into OnBusy, it defaults to OnBusyRestart
+func ParseOnBusy(s string) (OnBusy, error) {
+	switch OnBusy(s) {
+	case "", OnBusyRestart:
+		return OnBusyRestart, nil
+	case OnBusyQueue, OnBusyIgnore:
+		return OnBusy(s), nil
+	default:
+		return "", fmt.Errorf("invalid on-busy policy (%s), must be one of restart, queue or ignore", s)
+	}
+}
+
+// name is the name of i-th command, if any
+func (cg CommandGroup) name(i int) string {
+	if i < len(cg.Names) {
+		return cg.Names[i]
+	}
+	return ""
+}
+
 type CmdExecutor struct {
 	logger    *slog.Logger
 	parentCtx context.Context
 	commands  []CommandGroup
 	parallel  bool
 
+	// buildCommands are run before commands, and commands are (re)started only if they succeed
+	buildCommands []CommandGroup
+
 	interactive bool
+	// terminal (if not nil) is handed to commands, in interactive mode
+	terminal *terminal
 
-	mu sync.Mutex
+	noInitialRun bool
+
+	onBusy OnBusy
+
+	output *OutputMux
+
+	procAttrs ProcessAttrs
+
+	// cgroups (if not nil) places every command in its own cgroup
+	cgroups *cgroupManager
+
+	// portTimeout is how long commands wait for their ports to be released
+	portTimeout time.Duration
 
-	kill func() error
+	pty bool
+
+	// reaper (if not nil) tracks processes of commands, so that the ones that escaped, get killed and reaped too
+	reaper *reaper
+
+	// envFiles are absolute paths of env files, they are (re)loaded on every run
+	envFiles []string
+
+	mu sync.Mutex
+	// fileEnv are the values, last loaded from env files
+	fileEnv map[string]string
+	// generation is bumped on every (re)start, so that a superseded (re)start can back off
+	generation int
+	// finished is the last generation, whose build failed, or whose commands exited
+	finished int
+	// queued tells, that an event arrived while busy, with OnBusyQueue
+	queued   bool
+	building *execution
+	running  *execution
 }
 
 type CmdExecutorArgs struct {
-	Logger      *slog.Logger
-	Commands    []CommandGroup
-	Parallel    bool
+	Logger   *slog.Logger
+	Commands []CommandGroup
+	Parallel bool
+
+	// BuildCommands (if any) are run before Commands, on start and on every watch event. Running commands
+	// are replaced only if the build succeeds, otherwise they keep running, i.e. last good build keeps being served
+	BuildCommands []CommandGroup
+
+	// Interactive passes stdin to commands. If stdin is a terminal, commands run in its foreground (so, they get
+	// keyboard signals, like ctrl+c, too), and fwatcher takes it back (restoring its modes), once they exit or restart
 	Interactive bool
+
+	// NoInitialRun skips running commands on Start, i.e. they are run only after the first watch event
+	NoInitialRun bool
+
+	// OnBusy is what to do with watch events, that arrive while commands are running, defaults to OnBusyRestart
+	OnBusy OnBusy
+
+	// Output (optional) multiplexes output of commands, prefixing each line with command's name
+	Output *OutputMux
+
+	// EnvFiles (optional) are dotenv files, whose values are set in env of commands (later files override earlier ones).
+	// They are re-read on every run, and a watch event for any of them restarts all the commands
+	EnvFiles []string
+
+	// ProcessAttrs are working directory, credentials, umask and resource limits of all the commands
+	ProcessAttrs ProcessAttrs
+
+	// Cgroup configures cgroups (v2), that commands are placed in, on linux
+	Cgroup CgroupOptions
+
+	// Subreaper makes the current process a child subreaper (on linux, process wide), so that processes daemonized by
+	// commands get reparented to it, instead of init. Every process of a command gets tracked, and the ones it left
+	// behind are killed (and reaped) on restart. Child processes, that are not started by executors, are never reaped.
+	Subreaper bool
+
+	// PortTimeout is how long a command waits for its ports to be released, before it fails, defaults to DefaultPortTimeout
+	PortTimeout time.Duration
+
+	// PTY runs every command attached to a pseudo terminal, so that they keep their colors, and such. Their stderr gets
+	// merged into stdout, and it takes precedence over Interactive
+	PTY bool
 }
 
 func NewCmdExecutor(ctx context.Context, args CmdExecutorArgs) *CmdExecutor {
@@ -43,23 +175,248 @@ func NewCmdExecutor(ctx context.Context, args CmdExecutorArgs) *CmdExecutor {
 		args.Logger = slog.Default()
 	}
 
+	if args.OnBusy == "" {
+		args.OnBusy = OnBusyRestart
+	}
+
+	if args.PortTimeout == 0 {
+		args.PortTimeout = DefaultPortTimeout
+	}
+
+	envFiles := make([]string, 0, len(args.EnvFiles))
+	for _, f := range args.EnvFiles {
+		envFiles = append(envFiles, absPath(f))
+	}
+
+	var r *reaper
+	if args.Subreaper {
+		var err error
+		if r, err = newReaper(); err != nil {
+			args.Logger.Warn("failed to become a subreaper, escaped processes of commands will not be killed", "err", err)
+		}
+	}
+
+	var term *terminal
+	if args.Interactive && !args.PTY {
+		var err error
+		if term, err = openTerminal(os.Stdin); err != nil {
+			args.Logger.Warn("commands will not be run in foreground of the terminal", "err", err)
+		}
+	}
+
 	return &CmdExecutor{
-		parentCtx:   ctx,
-		logger:      args.Logger,
-		commands:    args.Commands,
-		parallel:    args.Parallel,
-		mu:          sync.Mutex{},
-		interactive: args.Interactive,
+		parentCtx:     ctx,
+		logger:        args.Logger,
+		commands:      expandCommandPatterns(args.Commands),
+		parallel:      args.Parallel,
+		buildCommands: args.BuildCommands,
+		mu:            sync.Mutex{},
+		interactive:   args.Interactive,
+		terminal:      term,
+		noInitialRun:  args.NoInitialRun,
+		onBusy:        args.OnBusy,
+		output:        args.Output,
+		envFiles:      envFiles,
+		procAttrs:     args.ProcessAttrs,
+		cgroups:       newCgroupManager(args.Cgroup, args.Logger),
+		reaper:        r,
+		portTimeout:   args.PortTimeout,
+		pty:           args.PTY,
+	}
+}
+
+// execution is a single run of a list of command groups, that can be cancelled as a whole
+type execution struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+	err    error
+	slots  *slots
+	// fileEnv are the values loaded from env files, when execution started. Commands read them from here, instead of
+	// from the executor, as its lock is held while the execution is being cancelled
+	fileEnv map[string]string
+
+	mu sync.Mutex
+	// leftovers kill processes, that commands left behind, when they exited
+	leftovers []func()
+}
+
+type executionKey struct{}
+
+// leftBehind registers kill, to kill processes left behind by a command, once execution gets cancelled
+func (e *execution) leftBehind(kill func()) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.leftovers = append(e.leftovers, kill)
+}
+
+// Cancel cancels the execution, and waits for all of its processes (including the ones left behind) to exit
+func (e *execution) Cancel() {
+	e.cancel()
+	<-e.done
+
+	e.mu.Lock()
+	leftovers := e.leftovers
+	e.leftovers = nil
+	e.mu.Unlock()
+
+	for _, kill := range leftovers {
+		kill()
 	}
 }
 
+// execute runs groups in a new execution, with env files freshly loaded. It must be called with ex.mu held
+func (ex *CmdExecutor) execute(groups []CommandGroup, parallel bool) *execution {
+	ex.loadEnvFiles()
+
+	ctx, cf := context.WithCancel(ex.parentCtx)
+	e := &execution{cancel: cf, done: make(chan struct{}), slots: newSlots(), fileEnv: ex.fileEnv}
+	ctx = context.WithValue(ctx, executionKey{}, e)
+
+	go func() {
+		defer close(e.done)
+		defer cf()
+		e.err = ex.execCommandGroups(ctx, e.slots, groups, parallel)
+		e.slots.wait()
+	}()
+
+	return e
+}
+
 // OnWatchEvent implements Executor.
 func (ex *CmdExecutor) OnWatchEvent(ev Event) error {
-	ex.Stop()
-	go ex.Start()
+	ex.mu.Lock()
+	defer ex.mu.Unlock()
+
+	if ex.finished != ex.generation {
+		switch ex.onBusy {
+		case OnBusyQueue:
+			ex.logger.Debug("commands are running, queued", "event", ev.Source)
+			ex.queued = true
+			return nil
+		case OnBusyIgnore:
+			ex.logger.Debug("commands are running, ignored", "event", ev.Source)
+			return nil
+		}
+	}
+
+	var slots []string
+	if isEnvFile(ex.envFiles, ev.Source) {
+		ex.logger.Debug("env file changed, restarting all commands", "event", ev.Source)
+	} else if hasPatterns(ex.commands) {
+		slots = affectedSlots("", ex.commands, ex.parallel, ev.Source)
+		if len(slots) == 0 {
+			ex.logger.Debug("no commands are affected", "event", ev.Source)
+			return nil
+		}
+	}
+
+	gen := ex.nextGeneration()
+	go ex.buildAndRestart(gen, slots)
 	return nil
 }
 
+// nextGeneration supersedes any in-flight (re)start, and cancels its build, if one is running.
+// It must be called with ex.mu held
+func (ex *CmdExecutor) nextGeneration() int {
+	ex.generation++
+	if ex.building != nil {
+		ex.building.cancel()
+	}
+	return ex.generation
+}
+
+// buildAndRun builds (if there are build commands), and replaces running commands with a new run, only if build succeeds.
+// It returns nil execution, if it got superseded by a newer (re)start
+func (ex *CmdExecutor) buildAndRun(gen int) (*execution, error) {
+	return ex.buildAndRestart(gen, nil)
+}
+
+// buildAndRestart is buildAndRun, that restarts only the given slots of running commands (if they are still running),
+// instead of replacing all of them. With no slots, or with the root slot (""), it replaces all of them
+func (ex *CmdExecutor) buildAndRestart(gen int, slots []string) (*execution, error) {
+	if len(ex.buildCommands) > 0 {
+		ex.mu.Lock()
+		// INFO: a cancelled build, might still be killing its processes, and new build must not overlap with it
+		if prev := ex.building; prev != nil {
+			ex.mu.Unlock()
+			<-prev.done
+			ex.mu.Lock()
+		}
+
+		if gen != ex.generation {
+			ex.mu.Unlock()
+			return nil, nil
+		}
+		build := ex.execute(ex.buildCommands, false)
+		ex.building = build
+		ex.mu.Unlock()
+
+		<-build.done
+
+		ex.mu.Lock()
+		if ex.building == build {
+			ex.building = nil
+		}
+		superseded := gen != ex.generation
+		ex.mu.Unlock()
+
+		if superseded {
+			ex.logger.Debug("build superseded by a newer change")
+			return nil, nil
+		}
+
+		if build.err != nil {
+			ex.logger.Error("[BUILD FAILED] keeping the last successful build running", "err", build.err)
+			ex.finish(gen)
+			return nil, build.err
+		}
+	}
+
+	ex.mu.Lock()
+	defer ex.mu.Unlock()
+
+	if gen != ex.generation {
+		return nil, nil
+	}
+
+	if run := ex.running; run != nil && len(slots) > 0 && !slices.Contains(slots, "") && run.slots.restart(slots...) {
+		ex.logger.Debug("restarted affected commands", "slots", slots)
+		go func() {
+			<-run.done
+			ex.finish(gen)
+		}()
+		return run, nil
+	}
+
+	if ex.running != nil {
+		ex.running.Cancel()
+	}
+
+	run := ex.execute(ex.commands, ex.parallel)
+	ex.running = run
+	go func() {
+		<-run.done
+		ex.finish(gen)
+	}()
+	return run, nil
+}
+
+// finish marks (re)start gen as finished, and starts commands once more, if an event got queued meanwhile
+func (ex *CmdExecutor) finish(gen int) {
+	ex.mu.Lock()
+	defer ex.mu.Unlock()
+
+	if gen != ex.generation {
+		return
+	}
+
+	ex.finished = gen
+	if ex.queued {
+		ex.queued = false
+		go ex.buildAndRun(ex.nextGeneration())
+	}
+}
+
 func killPID(pid int, logger *slog.Logger) error {
 	logger.Debug("about to kill", "process", pid)
 	if err := syscall.Kill(-pid, syscall.SIGKILL); err != nil {
@@ -72,18 +429,54 @@ func killPID(pid int, logger *slog.Logger) error {
 	return nil
 }
 
+// displayCmd is cmd, as it is to be shown in logs, i.e. script for `sh -c <script>` commands
+func displayCmd(cmd *exec.Cmd) string {
+	if len(cmd.Args) > 2 && cmd.Args[1] == "-c" {
+		return strings.Join(cmd.Args[2:], " ")
+	}
+	return strings.Join(cmd.Args, " ")
+}
+
+// displayName is name of cmd, for its output, when command group has no name for it
+func displayName(cmd *exec.Cmd) string {
+	name := []rune(displayCmd(cmd))
+	if len(name) > 20 {
+		return string(name[:19]) + "…"
+	}
+	return string(name)
+}
+
 type execArgs struct {
+	// Name of the command, for its output, when it is multiplexed
+	Name     string
+	Logger   *slog.Logger
 	PreExec  func(cmd *exec.Cmd)
 	PostExec func(cmd *exec.Cmd)
+	// OnStart is called, once the process has started
+	OnStart func(cmd *exec.Cmd)
+	// Env of the command, on top of env files
+	Env map[string]string
+	// Attrs (optional) of the process, on top of executor's process attributes
+	Attrs *ProcessAttrs
+	// Ports (optional) that command binds, it is started once they are free
+	Ports []int
 }
 
-func (ex *CmdExecutor) exec(newCmd func(context.Context) *exec.Cmd, args execArgs) error {
-	if err := ex.parentCtx.Err(); err != nil {
+// exec runs a command, until it exits, or ctx is cancelled, in which case its whole process group is killed.
+// PostExec is called once the process has exited.
+func (ex *CmdExecutor) exec(ctx context.Context, newCmd func(context.Context) *exec.Cmd, args execArgs) error {
+	if err := ctx.Err(); err != nil {
 		return err
 	}
 
-	ctx, cf := context.WithCancel(ex.parentCtx)
-	defer cf()
+	if len(args.Ports) > 0 {
+		if err := waitForPorts(ctx, args.Ports, ex.portTimeout, args.Logger); err != nil {
+			if ctx.Err() == nil {
+				args.Logger.Error("failed to start command", "err", err)
+			}
+			return err
+		}
+	}
 
 	cmd := newCmd(ctx)
 	if cmd == nil {
@@ -93,111 +486,250 @@ func (ex *CmdExecutor) exec(newCmd func(context.Context) *exec.Cmd, args execArg
 	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
 	if ex.interactive {
 		cmd.Stdin = os.Stdin
-		cmd.SysProcAttr.Foreground = true
 	}
 
 	if args.PreExec != nil {
 		args.PreExec(cmd)
 	}
 
-	if err := cmd.Start(); err != nil {
+	var fileEnv map[string]string
+	if e, ok := ctx.Value(executionKey{}).(*execution); ok {
+		fileEnv = e.fileEnv
+	}
+	withEnv(cmd, fileEnv, args.Env)
+
+	attrs := ex.procAttrs.merge(args.Attrs)
+	if err := attrs.apply(cmd); err != nil {
 		return err
 	}
 
-	logger := ex.logger.With("pid", cmd.Process.Pid, "cmd", strings.Join(strings.Split(cmd.String(), " ")[2:], " "))
+	var marker string
+	if ex.reaper != nil {
+		marker = newTrackMarker()
+		if cmd.Env == nil {
+			cmd.Env = os.Environ()
+		}
+		cmd.Env = append(cmd.Env, trackEnv+"="+marker)
+	}
+
+	if err := attrs.shim(cmd); err != nil {
+		return err
+	}
 
-	logger.Debug("process started")
+	if ex.output != nil {
+		name := args.Name
+		if name == "" {
+			name = displayName(cmd)
+		}
 
-	pid := cmd.Process.Pid
+		if cmd.Stdout != nil {
+			stdout := ex.output.Writer(name, cmd.Stdout)
+			cmd.Stdout = stdout
+			defer stdout.Flush()
+		}
 
-	ex.kill = func() error {
-		return killPID(pid, logger)
+		if cmd.Stderr != nil {
+			stderr := ex.output.Writer(name, cmd.Stderr)
+			cmd.Stderr = stderr
+			defer stderr.Flush()
+		}
 	}
 
-	exitErr := make(chan error, 1)
+	var ptyProc *ptyProcess
+	if ex.pty {
+		p, err := attachPTY(cmd)
+		if err != nil {
+			return err
+		}
+		ptyProc = p
+		defer ptyProc.close()
+	}
 
-	go func() {
-		if err := cmd.Wait(); err != nil {
-			exitErr <- err
-			logger.Debug("process finished (wait completed), got", "err", err)
+	var cg *cgroup
+	if ex.cgroups != nil {
+		c, err := ex.cgroups.create()
+		if err != nil {
+			args.Logger.Warn("failed to create cgroup, only process group of command will be killed on restart", "err", err)
+		} else {
+			cg = c
+			cg.attach(cmd)
 		}
-		exitErr <- nil
-	}()
+	}
 
-	select {
-	case <-ctx.Done():
-		logger.Debug("process finished (context cancelled)", "reason", ctx.Err())
+	start := (*exec.Cmd).Start
+	if ex.terminal != nil {
+		start = ex.terminal.start(start)
+	}
 
-	case err := <-exitErr:
-		if err == nil {
-			// INFO: command exited with non-zero exit code
-			logger.Debug("command SUCCESS", "exit.code", 0)
-			return nil
+	var err error
+	if ex.reaper != nil {
+		err = ex.reaper.start(cmd, start)
+	} else {
+		err = start(cmd)
+	}
+	if cg != nil {
+		cg.started()
+	}
+	if ptyProc != nil {
+		ptyProc.started()
+	}
+	if err != nil {
+		if cg != nil {
+			cg.remove()
 		}
+		return err
+	}
+
+	logger := args.Logger.With("pid", cmd.Process.Pid, "cmd", displayCmd(cmd))
 
-		logger.Error("command failed", "err", err)
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			logger.Debug("process finished", "exit.code", exitErr.ExitCode())
-			if exitErr.ExitCode() != 0 {
-				return err
+	if ex.terminal != nil {
+		defer func() {
+			if err := ex.terminal.release(cmd.Process.Pid); err != nil {
+				logger.Error("failed to take back the terminal", "err", err)
 			}
+		}()
+	}
+
+	if cg != nil {
+		defer ex.releaseCgroup(ctx, cg, logger)
+	}
+
+	if ex.reaper != nil {
+		defer ex.releaseTracked(ctx, marker, logger)
+	}
+
+	pid := cmd.Process.Pid
+
+	wait := func() error {
+		err := cmd.Wait()
+		if ex.reaper != nil {
+			ex.reaper.exited(pid)
 		}
-	case <-ex.parentCtx.Done():
-		logger.Debug("process finished (parent context cancelled)")
+		return err
 	}
 
-	if ex.interactive {
-		// Send SIGTERM to the interactive process, as user will see it on his screen
-		proc, err := os.FindProcess(os.Getpid())
-		if err != nil {
-			return err
+	logger.Debug("process started")
+
+	if args.OnStart != nil {
+		args.OnStart(cmd)
+	}
+
+	exitErr := make(chan error, 1)
+
+	go func() {
+		err := wait()
+		logger.Debug("process finished (wait completed), got", "err", err)
+		exitErr <- err
+	}()
+
+	defer func() {
+		if args.PostExec != nil {
+			args.PostExec(cmd)
 		}
+		logger.Debug("command fully executed and processed")
+	}()
 
-		err = proc.Signal(syscall.SIGTERM)
-		if err != nil {
-			if err != syscall.ESRCH {
-				logger.Error("failed to kill", "err", err)
-				return err
+	exited := false
+
+	select {
+	case err := <-exitErr:
+		if ctx.Err() == nil {
+			if err == nil {
+				logger.Debug("command SUCCESS", "exit.code", 0)
+				return nil
+			}
+
+			logger.Error("command failed", "err", err)
+			if exitErr, ok := err.(*exec.ExitError); ok {
+				logger.Debug("process finished", "exit.code", exitErr.ExitCode())
 			}
 			return err
 		}
+
+		// INFO: process got killed by exec.CommandContext, but rest of its process group still needs to be killed
+		exited = true
+		logger.Debug("process finished (context cancelled)", "reason", ctx.Err())
+
+	case <-ctx.Done():
+		logger.Debug("process finished (context cancelled)", "reason", ctx.Err())
 	}
 
-	if err := ex.kill(); err != nil {
+	if err := killPID(pid, logger); err != nil {
 		return err
 	}
 
-	if args.PostExec != nil {
-		args.PostExec(cmd)
+	if !exited {
+		// INFO: waiting for the process to actually exit, so that a restarted process does not overlap with it
+		<-exitErr
 	}
+	return ctx.Err()
+}
 
-	logger.Debug("command fully executed and processed")
-	return nil
+// releaseCgroup removes cgroup of an exited command, killing all of its processes, if command got cancelled. Otherwise,
+// processes it left behind (like daemons) keep running, until its execution gets cancelled, i.e. on restart
+func (ex *CmdExecutor) releaseCgroup(ctx context.Context, cg *cgroup, logger *slog.Logger) {
+	if peak, ok := cg.peakMemory(); ok {
+		logger.Info("[PEAK MEMORY] " + formatBytes(peak))
+	}
+
+	remove := func() {
+		if err := cg.remove(); err != nil {
+			logger.Error("failed to remove cgroup", "err", err)
+		}
+	}
+
+	e, ok := ctx.Value(executionKey{}).(*execution)
+	if ctx.Err() != nil || !ok || !cg.populated() {
+		remove()
+		return
+	}
+
+	logger.Debug("command left processes behind, they will be killed on restart")
+	e.leftBehind(remove)
 }
 
-func (ex *CmdExecutor) execCommandGroup(cg CommandGroup) error {
+// parallelErrors collects errors of commands (or, command groups), that run in parallel
+type parallelErrors struct {
+	mu   sync.Mutex
+	errs []error
+}
+
+func (pe *parallelErrors) add(err error) {
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+	pe.errs = append(pe.errs, err)
+}
+
+func (pe *parallelErrors) join() error {
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+	return errors.Join(pe.errs...)
+}
+
+// execCommandGroup runs command group cg, at key in the commands tree. Its sub groups run in slots, when they are parallel
+func (ex *CmdExecutor) execCommandGroup(ctx context.Context, slots *slots, key string, cg CommandGroup, logger *slog.Logger) error {
 	if cg.Parallel {
 		var wg sync.WaitGroup
+		var errs parallelErrors
 
-		ex.logger.Debug("PARALLEL", "len(cmds)", len(cg.Commands))
+		logger.Debug("PARALLEL", "len(cmds)", len(cg.Commands))
 		for i := range cg.Commands {
 			cmd := cg.Commands[i]
 			wg.Add(1)
 			go func() {
 				defer wg.Done()
 
-				ce := CmdExecutor{
-					logger:      ex.logger.With("executor", i),
-					parentCtx:   ex.parentCtx,
-					interactive: ex.interactive,
-					mu:          sync.Mutex{},
-				}
-
-				if err := ce.exec(cmd, execArgs{
+				if err := ex.exec(ctx, cmd, execArgs{
+					Name:     cg.name(i),
+					Logger:   logger.With("executor", i),
 					PreExec:  cg.PreExecCommand,
 					PostExec: cg.PostExecCommmand,
+					Env:      cg.env(i),
+					Attrs:    cg.ProcessAttrs,
+					Ports:    cg.ports(i),
 				}); err != nil {
-					ex.logger.Debug("command failed, got", "err", err)
+					logger.Debug("command failed, got", "err", err)
+					errs.add(err)
 					return
 				}
 			}()
@@ -205,76 +737,101 @@ func (ex *CmdExecutor) execCommandGroup(cg CommandGroup) error {
 
 		for i := range cg.Groups {
 			grp := cg.Groups[i]
+			k := slotKey(key, i)
 			wg.Add(1)
 			go func() {
 				defer wg.Done()
-				if err := ex.execCommandGroup(grp); err != nil {
-					ex.logger.Debug("command group execution failed, got", "err", err)
+				if err := slots.run(ctx, k, func(ctx context.Context) error {
+					return ex.execCommandGroup(ctx, slots, k, grp, logger)
+				}); err != nil {
+					logger.Debug("command group execution failed, got", "err", err)
+					errs.add(err)
 					return
 				}
 			}()
 		}
 
 		wg.Wait()
-		return nil
+		return errs.join()
+	}
+
+	// INFO: services keep running alongside the next commands, and are stopped if any of them fails
+	servicesCtx, stopServices := context.WithCancel(ctx)
+	defer stopServices()
+
+	var services []<-chan error
+	failed := func(err error) error {
+		stopServices()
+		waitServices(services)
+		return err
 	}
 
 	for i := range cg.Commands {
 		cmd := cg.Commands[i]
-		if err := ex.exec(cmd, execArgs{
+		args := execArgs{
+			Name:     cg.name(i),
+			Logger:   logger,
 			PreExec:  cg.PreExecCommand,
 			PostExec: cg.PostExecCommmand,
-		}); err != nil {
-			return err
+			Env:      cg.env(i),
+			Attrs:    cg.ProcessAttrs,
+			Ports:    cg.ports(i),
+		}
+
+		if cg.kind(i) == KindService {
+			exited, err := ex.startService(servicesCtx, cmd, cg.readyCheck(i), cg.readyTimeout(i), args)
+			if err != nil {
+				return failed(err)
+			}
+			services = append(services, exited)
+			continue
+		}
+
+		if err := ex.exec(ctx, cmd, args); err != nil {
+			return failed(err)
 		}
 	}
 
 	for i := range cg.Groups {
 		grp := cg.Groups[i]
-		if err := ex.execCommandGroup(grp); err != nil {
-			ex.logger.Debug("command group execution failed, got", "err", err)
-			return err
+		if err := ex.execCommandGroup(ctx, slots, slotKey(key, i), grp, logger); err != nil {
+			logger.Debug("command group execution failed, got", "err", err)
+			return failed(err)
 		}
 	}
 
-	return nil
+	return waitServices(services)
 }
 
-// Start implements Executor.
-func (ex *CmdExecutor) Start() error {
-	ex.mu.Lock()
-	defer ex.mu.Unlock()
-
-	if ex.parallel {
+func (ex *CmdExecutor) execCommandGroups(ctx context.Context, slots *slots, groups []CommandGroup, parallel bool) error {
+	if parallel {
 		var wg sync.WaitGroup
+		var errs parallelErrors
 
-		for i := range ex.commands {
-			cg := ex.commands[i]
+		for i := range groups {
+			cg := groups[i]
+			k := slotKey("", i)
 			wg.Add(1)
 			go func() {
 				defer wg.Done()
 
-				ce := CmdExecutor{
-					logger:      ex.logger.With("executor", i),
-					parentCtx:   ex.parentCtx,
-					interactive: ex.interactive,
-					mu:          sync.Mutex{},
-				}
-
-				if err := ce.execCommandGroup(cg); err != nil {
+				if err := slots.run(ctx, k, func(ctx context.Context) error {
+					return ex.execCommandGroup(ctx, slots, k, cg, ex.logger.With("executor", i))
+				}); err != nil {
 					ex.logger.Debug("exec command group, got", "err", err)
+					errs.add(err)
 					return
 				}
 			}()
 		}
 
 		wg.Wait()
-		return nil
+		return errs.join()
 	}
 
-	for i := range ex.commands {
-		cg := ex.commands[i]
-		if err := ex.execCommandGroup(cg); err != nil {
+	for i := range groups {
+		cg := groups[i]
+		if err := ex.execCommandGroup(ctx, slots, slotKey("", i), cg, ex.logger); err != nil {
 			return err
 		}
 	}
@@ -282,11 +839,56 @@ func (ex *CmdExecutor) Start() error {
 	return nil
 }
 
+// Start implements Executor.
+// It builds (if there are build commands), and runs commands, and waits for them to finish.
+// With NoInitialRun, it returns right away, and commands are run on watch events.
+func (ex *CmdExecutor) Start() error {
+	if ex.noInitialRun {
+		ex.logger.Debug("skipping initial run, waiting for changes")
+		return nil
+	}
+
+	ex.mu.Lock()
+	gen := ex.nextGeneration()
+	ex.mu.Unlock()
+
+	run, err := ex.buildAndRun(gen)
+	if err != nil {
+		return err
+	}
+
+	if run == nil {
+		return nil
+	}
+
+	<-run.done
+	if errors.Is(run.err, context.Canceled) || errors.Is(run.err, context.DeadlineExceeded) {
+		// INFO: it has been stopped, or restarted
+		return nil
+	}
+	return run.err
+}
+
 // Stop implements Executor.
+// It cancels in-flight build, and running commands, and waits for their processes to exit
 func (ex *CmdExecutor) Stop() error {
-	if ex.kill != nil {
-		return ex.kill()
+	ex.mu.Lock()
+	defer ex.mu.Unlock()
+
+	ex.generation++
+	ex.finished = ex.generation
+	ex.queued = false
+
+	if ex.building != nil {
+		ex.building.Cancel()
+		ex.building = nil
 	}
+
+	if ex.running != nil {
+		ex.running.Cancel()
+		ex.running = nil
+	}
+
 	return nil
 }
 
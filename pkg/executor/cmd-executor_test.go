@@ -3,13 +3,16 @@ package executor
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/nxtcoder17/go.pkgs/log"
 )
@@ -25,6 +28,27 @@ func (w *Writer) Write(b []byte) (int, error) {
 	return w.b.Write(b)
 }
 
+// shCmd is a command, that runs script with sh, writing its output to stdout
+func shCmd(stdout io.Writer, script string) func(c context.Context) *exec.Cmd {
+	return func(c context.Context) *exec.Cmd {
+		cmd := exec.CommandContext(c, "sh", "-c", script)
+		cmd.Stdout = stdout
+		cmd.Stderr = os.Stderr
+		return cmd
+	}
+}
+
+// count counts occurrences of word, among whitespace separated words of s
+func count(s string, word string) int {
+	n := 0
+	for _, f := range strings.Fields(s) {
+		if f == word {
+			n++
+		}
+	}
+	return n
+}
+
 func Test_Exectuor_Start(t *testing.T) {
 	newCmd := func(stdout io.Writer, cmd string, args ...string) func(c context.Context) *exec.Cmd {
 		return func(c context.Context) *exec.Cmd {
@@ -193,3 +217,374 @@ func Test_Exectuor_Start(t *testing.T) {
 		})
 	}
 }
+
+func Test_Executor_ParallelErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		groups  bool
+		scripts []string
+		wantErr bool
+	}{
+		{
+			name:    "1. parallel commands, with one failing",
+			scripts: []string{"exit 1", "true"},
+			wantErr: true,
+		},
+		{
+			name:    "2. parallel groups, with one failing",
+			groups:  true,
+			scripts: []string{"exit 1", "true"},
+			wantErr: true,
+		},
+		{
+			name:    "3. parallel groups, with none failing",
+			groups:  true,
+			scripts: []string{"true", "true"},
+			wantErr: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cmds []func(c context.Context) *exec.Cmd
+			for _, script := range tt.scripts {
+				cmds = append(cmds, shCmd(io.Discard, script))
+			}
+
+			commands := []CommandGroup{{Commands: cmds, Parallel: true}}
+			if tt.groups {
+				commands = nil
+				for _, cmd := range cmds {
+					commands = append(commands, CommandGroup{Commands: []func(c context.Context) *exec.Cmd{cmd}})
+				}
+			}
+
+			ex := NewCmdExecutor(context.TODO(), CmdExecutorArgs{
+				Logger:   log.New(log.Options{ShowDebugLogs: os.Getenv("DEBUG") == "true"}),
+				Commands: commands,
+				Parallel: tt.groups,
+			})
+
+			err := ex.Start()
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) != tt.wantErr {
+				t.Errorf("FAILED (%s)\n\t got: %v\n\twant error: %v\n", tt.name, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_Executor_BuildAndRun(t *testing.T) {
+	b := new(bytes.Buffer)
+	w := Writer{b: b, m: sync.Mutex{}}
+
+	// INFO: build fails, while this file exists
+	brokenFile := filepath.Join(t.TempDir(), "broken")
+
+	ctx, cf := context.WithCancel(context.TODO())
+	defer cf()
+
+	ex := NewCmdExecutor(ctx, CmdExecutorArgs{
+		Logger: log.New(log.Options{ShowDebugLogs: os.Getenv("DEBUG") == "true"}),
+		BuildCommands: []CommandGroup{
+			{Commands: []func(c context.Context) *exec.Cmd{shCmd(&w, fmt.Sprintf("test ! -f %s && echo built", brokenFile))}},
+		},
+		Commands: []CommandGroup{
+			{Commands: []func(c context.Context) *exec.Cmd{shCmd(&w, "echo started; sleep 5")}},
+		},
+	})
+
+	go ex.Start()
+	defer ex.Stop()
+
+	output := func() string {
+		<-time.After(300 * time.Millisecond)
+		w.m.Lock()
+		defer w.m.Unlock()
+		return strings.Join(strings.Fields(b.String()), ",")
+	}
+
+	if got, want := output(), "built,started"; got != want {
+		t.Fatalf("FAILED (initial build)\n\t got: %s\n\twant: %s\n", got, want)
+	}
+
+	if err := os.WriteFile(brokenFile, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ex.OnWatchEvent(Event{Source: "main.go"})
+
+	// INFO: build failed, so process from last build must keep running
+	if got, want := output(), "built,started"; got != want {
+		t.Fatalf("FAILED (failed build)\n\t got: %s\n\twant: %s\n", got, want)
+	}
+
+	ex.mu.Lock()
+	running := ex.running
+	ex.mu.Unlock()
+	select {
+	case <-running.done:
+		t.Fatalf("FAILED (failed build), process from last build is not running anymore")
+	default:
+	}
+
+	if err := os.Remove(brokenFile); err != nil {
+		t.Fatal(err)
+	}
+	ex.OnWatchEvent(Event{Source: "main.go"})
+
+	if got, want := output(), "built,started,built,started"; got != want {
+		t.Fatalf("FAILED (fixed build)\n\t got: %s\n\twant: %s\n", got, want)
+	}
+
+	select {
+	case <-running.done:
+	default:
+		t.Fatalf("FAILED (fixed build), process from last build should have been replaced")
+	}
+}
+
+func Test_Executor_CancelInFlightBuild(t *testing.T) {
+	tests := []struct {
+		name string
+		// events is the number of watch events, fired concurrently while a build is in-flight
+		events  int
+		stop    bool
+		built   int
+		started int
+	}{
+		{
+			name:    "1. single change during build, restarts build",
+			events:  1,
+			built:   1,
+			started: 1,
+		},
+		{
+			name:    "2. burst of changes during build, results in a single build",
+			events:  10,
+			built:   1,
+			started: 1,
+		},
+		{
+			name:    "3. stop during build, cancels build",
+			stop:    true,
+			built:   0,
+			started: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := new(bytes.Buffer)
+			w := Writer{b: b, m: sync.Mutex{}}
+
+			ctx, cf := context.WithCancel(context.TODO())
+			defer cf()
+
+			ex := NewCmdExecutor(ctx, CmdExecutorArgs{
+				Logger: log.New(log.Options{ShowDebugLogs: os.Getenv("DEBUG") == "true"}),
+				BuildCommands: []CommandGroup{
+					{Commands: []func(c context.Context) *exec.Cmd{shCmd(&w, "echo building; sleep 0.5; echo built")}},
+				},
+				Commands: []CommandGroup{
+					{Commands: []func(c context.Context) *exec.Cmd{shCmd(&w, "echo started; sleep 5")}},
+				},
+			})
+			defer ex.Stop()
+
+			startErr := make(chan error, 1)
+			go func() {
+				startErr <- ex.Start()
+			}()
+
+			// INFO: letting the initial build start, but not finish
+			<-time.After(200 * time.Millisecond)
+
+			if tt.stop {
+				ex.Stop()
+				select {
+				case err := <-startErr:
+					if err != nil {
+						t.Errorf("FAILED (%s)\n\t got: %v\n\twant: <nil>\n", tt.name, err)
+					}
+				case <-time.After(2 * time.Second):
+					t.Fatalf("FAILED (%s), Start did not return after Stop", tt.name)
+				}
+			}
+
+			var wg sync.WaitGroup
+			for i := 0; i < tt.events; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					ex.OnWatchEvent(Event{Source: "main.go"})
+				}()
+			}
+			wg.Wait()
+
+			<-time.After(1 * time.Second)
+
+			w.m.Lock()
+			out := b.String()
+			w.m.Unlock()
+
+			if got := count(out, "built"); got != tt.built {
+				t.Errorf("FAILED (%s) built count\n\t got: %d\n\twant: %d\n\toutput: %q", tt.name, got, tt.built, out)
+			}
+
+			if got := count(out, "started"); got != tt.started {
+				t.Errorf("FAILED (%s) started count\n\t got: %d\n\twant: %d\n\toutput: %q", tt.name, got, tt.started, out)
+			}
+
+			ex.mu.Lock()
+			building := ex.building
+			ex.mu.Unlock()
+			if building != nil {
+				t.Errorf("FAILED (%s), build is still in-flight", tt.name)
+			}
+		})
+	}
+}
+
+func Test_Executor_NoInitialRun(t *testing.T) {
+	b := new(bytes.Buffer)
+	w := Writer{b: b, m: sync.Mutex{}}
+
+	ex := NewCmdExecutor(context.TODO(), CmdExecutorArgs{
+		Logger: log.New(log.Options{ShowDebugLogs: os.Getenv("DEBUG") == "true"}),
+		Commands: []CommandGroup{
+			{
+				Commands: []func(c context.Context) *exec.Cmd{
+					func(c context.Context) *exec.Cmd {
+						cmd := exec.CommandContext(c, "echo", "hi")
+						cmd.Stdout = &w
+						return cmd
+					},
+				},
+			},
+		},
+		NoInitialRun: true,
+	})
+	defer ex.Stop()
+
+	output := func() string {
+		w.m.Lock()
+		defer w.m.Unlock()
+		return strings.TrimSpace(b.String())
+	}
+
+	if err := ex.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	<-time.After(200 * time.Millisecond)
+	if got, want := output(), ""; got != want {
+		t.Fatalf("FAILED (before watch event)\n\t got: %s\n\twant: %s\n", got, want)
+	}
+
+	ex.OnWatchEvent(Event{Source: "main.go"})
+
+	<-time.After(200 * time.Millisecond)
+	if got, want := output(), "hi"; got != want {
+		t.Fatalf("FAILED (after watch event)\n\t got: %s\n\twant: %s\n", got, want)
+	}
+}
+
+func Test_Executor_OnBusy(t *testing.T) {
+	tests := []struct {
+		name     string
+		onBusy   OnBusy
+		started  int
+		finished int
+	}{
+		{
+			name:     "1. restart, kills running command on every event",
+			onBusy:   OnBusyRestart,
+			started:  6,
+			finished: 1,
+		},
+		{
+			name:     "2. queue, runs once more after running command finishes",
+			onBusy:   OnBusyQueue,
+			started:  2,
+			finished: 2,
+		},
+		{
+			name:     "3. ignore, drops events while command is running",
+			onBusy:   OnBusyIgnore,
+			started:  1,
+			finished: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := new(bytes.Buffer)
+			w := Writer{b: b, m: sync.Mutex{}}
+
+			ex := NewCmdExecutor(context.TODO(), CmdExecutorArgs{
+				Logger: log.New(log.Options{ShowDebugLogs: os.Getenv("DEBUG") == "true"}),
+				Commands: []CommandGroup{
+					{
+						Commands: []func(c context.Context) *exec.Cmd{
+							func(c context.Context) *exec.Cmd {
+								cmd := exec.CommandContext(c, "sh", "-c", "echo started; sleep 0.6; echo finished")
+								cmd.Stdout = &w
+								return cmd
+							},
+						},
+					},
+				},
+				OnBusy: tt.onBusy,
+			})
+			defer ex.Stop()
+
+			go ex.Start()
+
+			// INFO: burst of events, while command is running
+			for i := 0; i < 5; i++ {
+				<-time.After(80 * time.Millisecond)
+				ex.OnWatchEvent(Event{Source: "main.go"})
+			}
+
+			<-time.After(1600 * time.Millisecond)
+
+			w.m.Lock()
+			out := b.String()
+			w.m.Unlock()
+
+			if got := count(out, "started"); got != tt.started {
+				t.Errorf("FAILED (%s) started count\n\t got: %d\n\twant: %d\n\toutput: %q", tt.name, got, tt.started, out)
+			}
+
+			if got := count(out, "finished"); got != tt.finished {
+				t.Errorf("FAILED (%s) finished count\n\t got: %d\n\twant: %d\n\toutput: %q", tt.name, got, tt.finished, out)
+			}
+		})
+	}
+}
+
+func Test_ParseOnBusy(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    OnBusy
+		wantErr bool
+	}{
+		{input: "", want: OnBusyRestart},
+		{input: "restart", want: OnBusyRestart},
+		{input: "queue", want: OnBusyQueue},
+		{input: "ignore", want: OnBusyIgnore},
+		{input: "kill", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseOnBusy(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("FAILED (%s)\n\t got err: %v\n\twant err: %v\n", tt.input, err, tt.wantErr)
+			continue
+		}
+
+		if got != tt.want {
+			t.Errorf("FAILED (%s)\n\t got: %s\n\twant: %s\n", tt.input, got, tt.want)
+		}
+	}
+}
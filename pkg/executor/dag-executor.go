@@ -0,0 +1,424 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// DAGNode is a node of DAGExecutor, it runs only after all of its dependencies have finished successfully
+type DAGNode struct {
+	Name      string
+	Commands  []CommandGroup
+	DependsOn []string
+
+	// Dirs (optional) scope the node, i.e. it re-runs only on changes inside them (or when one of its dependencies re-runs).
+	// With no dirs, it re-runs on every change
+	Dirs []string
+}
+
+// DAGExecutor runs nodes as per their dependencies, independent nodes are run concurrently.
+// On a watch event, only the affected nodes, and their downstream dependents are re-run
+type DAGExecutor struct {
+	logger *slog.Logger
+	runner *CmdExecutor
+
+	nodes map[string]*DAGNode
+	// order is a topological order of nodes
+	order []string
+	// dependents are the nodes, that directly depend on a node
+	dependents map[string][]string
+
+	noInitialRun bool
+
+	mu    sync.Mutex
+	round *dagRound
+	// running are the last executions of nodes, finished ones are kept too, so that processes they left behind get killed
+	// once the node re-runs
+	running map[string]*execution
+	// runs are done channels of the rounds, in which nodes were last started, so that a node can wait on its dependency
+	// that got started in an earlier round
+	runs map[string]chan struct{}
+	// results are the results of the last finished run of nodes, a node missing here has not finished successfully yet
+	results map[string]error
+}
+
+type DAGExecutorArgs struct {
+	Logger *slog.Logger
+	Nodes  []DAGNode
+
+	Interactive bool
+
+	// NoInitialRun skips running nodes on Start, i.e. they are run only after the first watch event
+	NoInitialRun bool
+
+	// Output (optional) multiplexes output of commands, prefixing each line with command's name
+	Output *OutputMux
+
+	// EnvFiles (optional) are dotenv files, whose values are set in env of commands. A watch event for any of them re-runs
+	// all the nodes
+	EnvFiles []string
+
+	// ProcessAttrs are working directory, credentials, umask and resource limits of all the commands
+	ProcessAttrs ProcessAttrs
+	// Cgroup configures cgroups (v2), that commands are placed in, on linux
+	Cgroup CgroupOptions
+	// Subreaper makes the current process a child subreaper, see CmdExecutorArgs.Subreaper
+	Subreaper bool
+	// PortTimeout is how long a command waits for its ports to be released, see CmdExecutorArgs.PortTimeout
+	PortTimeout time.Duration
+	// PTY runs every command attached to a pseudo terminal, see CmdExecutorArgs.PTY
+	PTY bool
+}
+
+// dagRound is a single (re)run of a set of affected nodes
+type dagRound struct {
+	ctx      context.Context
+	cancel   context.CancelFunc
+	affected map[string]struct{}
+	done     map[string]chan struct{}
+	// started are the nodes, whose commands have been started in this round, guarded by DAGExecutor.mu
+	started map[string]struct{}
+}
+
+// finished tells whether node has finished in this round
+func (r *dagRound) finished(name string) bool {
+	select {
+	case <-r.done[name]:
+		return true
+	default:
+		return false
+	}
+}
+
+// ErrDependencyFailed is the result of a node, that did not run, as one of its dependencies failed
+var ErrDependencyFailed = errors.New("dependency failed")
+
+func NewDAGExecutor(ctx context.Context, args DAGExecutorArgs) (*DAGExecutor, error) {
+	if args.Logger == nil {
+		args.Logger = slog.Default()
+	}
+
+	d := &DAGExecutor{
+		logger: args.Logger,
+		runner: NewCmdExecutor(ctx, CmdExecutorArgs{
+			Logger:       args.Logger,
+			Interactive:  args.Interactive,
+			Output:       args.Output,
+			EnvFiles:     args.EnvFiles,
+			ProcessAttrs: args.ProcessAttrs,
+			Cgroup:       args.Cgroup,
+			Subreaper:    args.Subreaper,
+			PortTimeout:  args.PortTimeout,
+			PTY:          args.PTY,
+		}),
+		nodes:        make(map[string]*DAGNode, len(args.Nodes)),
+		dependents:   make(map[string][]string, len(args.Nodes)),
+		noInitialRun: args.NoInitialRun,
+		running:      map[string]*execution{},
+		runs:         map[string]chan struct{}{},
+		results:      map[string]error{},
+	}
+
+	for i := range args.Nodes {
+		node := args.Nodes[i]
+		if _, ok := d.nodes[node.Name]; ok {
+			return nil, fmt.Errorf("duplicate node (%s)", node.Name)
+		}
+
+		dirs := make([]string, 0, len(node.Dirs))
+		for _, dir := range node.Dirs {
+			dirs = append(dirs, absPath(dir))
+		}
+		node.Dirs = dirs
+		d.nodes[node.Name] = &node
+	}
+
+	for _, node := range args.Nodes {
+		for _, dep := range node.DependsOn {
+			if _, ok := d.nodes[dep]; !ok {
+				return nil, fmt.Errorf("node (%s) depends on unknown node (%s)", node.Name, dep)
+			}
+			d.dependents[dep] = append(d.dependents[dep], node.Name)
+		}
+	}
+
+	order, err := topologicalOrder(args.Nodes)
+	if err != nil {
+		return nil, err
+	}
+	d.order = order
+
+	return d, nil
+}
+
+// topologicalOrder orders nodes, such that every node comes after its dependencies, it fails if there is a cycle
+func topologicalOrder(nodes []DAGNode) ([]string, error) {
+	byName := make(map[string]DAGNode, len(nodes))
+	for _, node := range nodes {
+		byName[node.Name] = node
+	}
+
+	const (
+		visiting = 1
+		visited  = 2
+	)
+
+	state := make(map[string]int, len(nodes))
+	order := make([]string, 0, len(nodes))
+
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("dependency cycle: %v", append(path, name))
+		}
+
+		state[name] = visiting
+		for _, dep := range byName[name].DependsOn {
+			if err := visit(dep, append(path, name)); err != nil {
+				return err
+			}
+		}
+		state[name] = visited
+		order = append(order, name)
+		return nil
+	}
+
+	for _, node := range nodes {
+		if err := visit(node.Name, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return order, nil
+}
+
+// downstream adds nodes, along with all of their (transitive) dependents to affected
+func (d *DAGExecutor) downstream(affected map[string]struct{}, names ...string) {
+	for _, name := range names {
+		if _, ok := affected[name]; ok {
+			continue
+		}
+		affected[name] = struct{}{}
+		d.downstream(affected, d.dependents[name]...)
+	}
+}
+
+// upstream adds (transitive) dependencies of names, that have never been started (like, with NoInitialRun), to affected,
+// as nodes would otherwise be skipped, for their dependencies not having succeeded. It must be called with d.mu held
+func (d *DAGExecutor) upstream(affected map[string]struct{}, names ...string) {
+	for _, name := range names {
+		for _, dep := range d.nodes[name].DependsOn {
+			if _, ok := affected[dep]; ok {
+				continue
+			}
+			if _, ok := d.runs[dep]; ok {
+				continue
+			}
+			affected[dep] = struct{}{}
+			d.upstream(affected, dep)
+		}
+	}
+}
+
+// run (re)runs affected nodes in a new round. Nodes of the previous round, that are still waiting on their dependencies,
+// are carried over to it
+func (d *DAGExecutor) run(affected map[string]struct{}) *dagRound {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if prev := d.round; prev != nil {
+		prev.cancel()
+		for name := range prev.affected {
+			if _, ok := prev.started[name]; !ok && !prev.finished(name) {
+				affected[name] = struct{}{}
+			}
+		}
+	}
+
+	for name := range affected {
+		if e, ok := d.running[name]; ok {
+			e.Cancel()
+			delete(d.running, name)
+		}
+		delete(d.results, name)
+	}
+
+	ctx, cf := context.WithCancel(context.TODO())
+	r := &dagRound{
+		ctx:      ctx,
+		cancel:   cf,
+		affected: affected,
+		done:     make(map[string]chan struct{}, len(affected)),
+		started:  make(map[string]struct{}, len(affected)),
+	}
+	for name := range affected {
+		r.done[name] = make(chan struct{})
+	}
+	d.round = r
+
+	for _, name := range d.order {
+		if _, ok := affected[name]; ok {
+			go d.runNode(r, name)
+		}
+	}
+
+	return r
+}
+
+// runNode waits for dependencies of node to finish, and runs it, if all of them succeeded
+func (d *DAGExecutor) runNode(r *dagRound, name string) {
+	defer close(r.done[name])
+
+	node := d.nodes[name]
+	logger := d.logger.With("node", name)
+
+	for _, dep := range node.DependsOn {
+		done, ok := r.done[dep]
+		if !ok {
+			d.mu.Lock()
+			done = d.runs[dep]
+			d.mu.Unlock()
+		}
+
+		if done != nil {
+			select {
+			case <-done:
+			case <-r.ctx.Done():
+				return
+			}
+		}
+
+		d.mu.Lock()
+		err, ok := d.results[dep]
+		d.mu.Unlock()
+
+		if !ok || err != nil {
+			logger.Error("[SKIPPED] as its dependency did not succeed", "dependency", dep)
+			d.mu.Lock()
+			if d.round == r {
+				d.results[name] = ErrDependencyFailed
+			}
+			d.mu.Unlock()
+			return
+		}
+	}
+
+	d.mu.Lock()
+	if d.round != r {
+		d.mu.Unlock()
+		return
+	}
+	d.runner.mu.Lock()
+	e := d.runner.execute(node.Commands, false)
+	d.runner.mu.Unlock()
+	d.running[name] = e
+	r.started[name] = struct{}{}
+	d.runs[name] = r.done[name]
+	d.mu.Unlock()
+
+	<-e.done
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// INFO: a node, that got re-run meanwhile, has its result coming from the newer run
+	if d.running[name] == e {
+		d.results[name] = e.err
+	}
+}
+
+// OnWatchEvent implements Executor.
+func (d *DAGExecutor) OnWatchEvent(ev Event) error {
+	envFileChanged := isEnvFile(d.runner.envFiles, ev.Source)
+
+	var names []string
+	for _, name := range d.order {
+		if envFileChanged || inDirs(d.nodes[name].Dirs, ev.Source) {
+			names = append(names, name)
+		}
+	}
+
+	if len(names) == 0 {
+		return nil
+	}
+
+	affected := map[string]struct{}{}
+	d.downstream(affected, names...)
+
+	d.mu.Lock()
+	for _, name := range d.order {
+		if _, ok := affected[name]; ok {
+			d.upstream(affected, name)
+		}
+	}
+	d.mu.Unlock()
+
+	go d.run(affected)
+	return nil
+}
+
+// Start implements Executor.
+// It runs all the nodes, and waits for them to finish. With NoInitialRun, it returns right away
+func (d *DAGExecutor) Start() error {
+	if d.noInitialRun {
+		d.logger.Debug("skipping initial run, waiting for changes")
+		return nil
+	}
+
+	affected := make(map[string]struct{}, len(d.order))
+	for _, name := range d.order {
+		affected[name] = struct{}{}
+	}
+
+	r := d.run(affected)
+
+	for _, name := range d.order {
+		<-r.done[name]
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.round != r {
+		// INFO: it has been stopped, or re-run
+		return nil
+	}
+
+	var errs []error
+	for _, name := range d.order {
+		err := d.results[name]
+
+		if err != nil && !errors.Is(err, context.Canceled) {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Stop implements Executor.
+func (d *DAGExecutor) Stop() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.round != nil {
+		d.round.cancel()
+		d.round = nil
+	}
+
+	for name, e := range d.running {
+		e.Cancel()
+		delete(d.running, name)
+	}
+
+	return nil
+}
+
+var _ Executor = (*DAGExecutor)(nil)
@@ -0,0 +1,304 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nxtcoder17/go.pkgs/log"
+)
+
+func Test_DAGExecutor(t *testing.T) {
+	// INFO: nodes echo their name, when they start, and when they finish
+	node := func(w *Writer, name string, script string, dependsOn ...string) DAGNode {
+		return DAGNode{
+			Name:      name,
+			DependsOn: dependsOn,
+			Commands: []CommandGroup{
+				{
+					Commands: []func(c context.Context) *exec.Cmd{
+						func(c context.Context) *exec.Cmd {
+							cmd := exec.CommandContext(c, "sh", "-c", "echo "+name+":start; "+script+"; echo "+name+":end")
+							cmd.Stdout = w
+							cmd.Stderr = os.Stderr
+							return cmd
+						},
+					},
+				},
+			},
+		}
+	}
+
+	// before checks, that a appears before b in output
+	before := func(out string, a, b string) bool {
+		i, j := strings.Index(out, a), strings.Index(out, b)
+		return i >= 0 && j >= 0 && i < j
+	}
+
+	t.Run("1. runs nodes after their dependencies, and independent nodes concurrently", func(t *testing.T) {
+		w := &Writer{b: new(bytes.Buffer)}
+
+		d, err := NewDAGExecutor(context.TODO(), DAGExecutorArgs{
+			Logger: log.New(log.Options{ShowDebugLogs: os.Getenv("DEBUG") == "true"}),
+			Nodes: []DAGNode{
+				node(w, "api", "sleep 0.3", "migrate"),
+				node(w, "worker", "sleep 0.3", "migrate"),
+				node(w, "migrate", "sleep 0.2"),
+				node(w, "build", "true", "codegen"),
+				node(w, "codegen", "sleep 0.2"),
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := d.Start(); err != nil {
+			t.Fatal(err)
+		}
+
+		out := w.b.String()
+		for _, edge := range [][2]string{
+			{"migrate:end", "api:start"},
+			{"migrate:end", "worker:start"},
+			{"codegen:end", "build:start"},
+			// INFO: api and worker are independent of each other
+			{"api:start", "worker:end"},
+			{"worker:start", "api:end"},
+		} {
+			if !before(out, edge[0], edge[1]) {
+				t.Errorf("FAILED\n\texpected (%s) before (%s), in output:\n%s", edge[0], edge[1], out)
+			}
+		}
+	})
+
+	t.Run("2. on a change, re-runs only affected node and its dependents", func(t *testing.T) {
+		w := &Writer{b: new(bytes.Buffer)}
+
+		root := t.TempDir()
+		codegen := node(w, "codegen", "true")
+		codegen.Dirs = []string{filepath.Join(root, "schema")}
+
+		migrate := node(w, "migrate", "true")
+		migrate.Dirs = []string{filepath.Join(root, "migrations")}
+
+		d, err := NewDAGExecutor(context.TODO(), DAGExecutorArgs{
+			Logger: log.New(log.Options{ShowDebugLogs: os.Getenv("DEBUG") == "true"}),
+			Nodes: []DAGNode{
+				migrate,
+				node(w, "api", "true", "migrate", "build"),
+				codegen,
+				node(w, "build", "true", "codegen"),
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := d.Start(); err != nil {
+			t.Fatal(err)
+		}
+
+		w.m.Lock()
+		w.b.Reset()
+		w.m.Unlock()
+
+		d.OnWatchEvent(Event{Source: filepath.Join(root, "schema", "user.graphql")})
+		<-time.After(500 * time.Millisecond)
+
+		w.m.Lock()
+		out := w.b.String()
+		w.m.Unlock()
+
+		for _, name := range []string{"codegen", "build", "api"} {
+			if !strings.Contains(out, name+":end") {
+				t.Errorf("FAILED\n\texpected (%s) to re-run, in output:\n%s", name, out)
+			}
+		}
+
+		if strings.Contains(out, "migrate:") {
+			t.Errorf("FAILED\n\texpected (migrate) not to re-run, in output:\n%s", out)
+		}
+	})
+
+	t.Run("3. skips dependents of a failed node", func(t *testing.T) {
+		w := &Writer{b: new(bytes.Buffer)}
+
+		d, err := NewDAGExecutor(context.TODO(), DAGExecutorArgs{
+			Logger: log.New(log.Options{ShowDebugLogs: os.Getenv("DEBUG") == "true"}),
+			Nodes: []DAGNode{
+				node(w, "migrate", "exit 1"),
+				node(w, "api", "true", "migrate"),
+				node(w, "codegen", "true"),
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = d.Start()
+		if !errors.Is(err, ErrDependencyFailed) {
+			t.Errorf("FAILED\n\t got: %v\n\twant: %v\n", err, ErrDependencyFailed)
+		}
+
+		out := w.b.String()
+		if strings.Contains(out, "api:start") {
+			t.Errorf("FAILED\n\texpected (api) to be skipped, in output:\n%s", out)
+		}
+
+		if !strings.Contains(out, "codegen:end") {
+			t.Errorf("FAILED\n\texpected (codegen) to run, in output:\n%s", out)
+		}
+	})
+
+	t.Run("4. on a change, while dependency is still running, waits for it", func(t *testing.T) {
+		w := &Writer{b: new(bytes.Buffer)}
+
+		root := t.TempDir()
+		api := node(w, "api", "sleep 5", "migrate")
+		api.Dirs = []string{filepath.Join(root, "api")}
+
+		migrate := node(w, "migrate", "sleep 0.4")
+		migrate.Dirs = []string{filepath.Join(root, "migrations")}
+
+		worker := node(w, "worker", "sleep 5")
+		worker.Dirs = []string{filepath.Join(root, "worker")}
+
+		d, err := NewDAGExecutor(context.TODO(), DAGExecutorArgs{
+			Logger: log.New(log.Options{ShowDebugLogs: os.Getenv("DEBUG") == "true"}),
+			Nodes:  []DAGNode{migrate, api, worker},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer d.Stop()
+
+		go d.Start()
+		<-time.After(100 * time.Millisecond)
+
+		d.OnWatchEvent(Event{Source: filepath.Join(root, "api", "main.go")})
+		<-time.After(600 * time.Millisecond)
+
+		w.m.Lock()
+		out := w.b.String()
+		w.m.Unlock()
+
+		if got := strings.Count(out, "api:start"); got != 1 {
+			t.Errorf("FAILED\n\texpected (api) to start once, after (migrate), in output:\n%s", out)
+		}
+
+		if !before(out, "migrate:end", "api:start") {
+			t.Errorf("FAILED\n\texpected (migrate:end) before (api:start), in output:\n%s", out)
+		}
+
+		// INFO: worker is not affected by the change, so it must not be restarted
+		if got := strings.Count(out, "worker:start"); got != 1 {
+			t.Errorf("FAILED\n\texpected (worker) to start once, in output:\n%s", out)
+		}
+	})
+
+	t.Run("5. with no initial run, on a change, runs dependencies that never ran", func(t *testing.T) {
+		w := &Writer{b: new(bytes.Buffer)}
+
+		root := t.TempDir()
+		api := node(w, "api", "true", "migrate")
+		api.Dirs = []string{filepath.Join(root, "api")}
+
+		migrate := node(w, "migrate", "true", "codegen")
+		migrate.Dirs = []string{filepath.Join(root, "migrations")}
+
+		codegen := node(w, "codegen", "true")
+		codegen.Dirs = []string{filepath.Join(root, "schema")}
+
+		worker := node(w, "worker", "true", "migrate")
+		worker.Dirs = []string{filepath.Join(root, "worker")}
+
+		d, err := NewDAGExecutor(context.TODO(), DAGExecutorArgs{
+			Logger:       log.New(log.Options{ShowDebugLogs: os.Getenv("DEBUG") == "true"}),
+			Nodes:        []DAGNode{codegen, migrate, api, worker},
+			NoInitialRun: true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer d.Stop()
+
+		if err := d.Start(); err != nil {
+			t.Fatal(err)
+		}
+
+		d.OnWatchEvent(Event{Source: filepath.Join(root, "api", "main.go")})
+		<-time.After(500 * time.Millisecond)
+
+		w.m.Lock()
+		out := w.b.String()
+		w.m.Unlock()
+
+		for _, edge := range [][2]string{
+			{"codegen:end", "migrate:start"},
+			{"migrate:end", "api:start"},
+		} {
+			if !before(out, edge[0], edge[1]) {
+				t.Errorf("FAILED\n\texpected (%s) before (%s), in output:\n%s", edge[0], edge[1], out)
+			}
+		}
+
+		// INFO: worker is not affected by the change
+		if strings.Contains(out, "worker:") {
+			t.Errorf("FAILED\n\texpected (worker) not to run, in output:\n%s", out)
+		}
+
+		w.m.Lock()
+		w.b.Reset()
+		w.m.Unlock()
+
+		// INFO: dependencies have run by now, so they are not re-run
+		d.OnWatchEvent(Event{Source: filepath.Join(root, "api", "main.go")})
+		<-time.After(500 * time.Millisecond)
+
+		w.m.Lock()
+		out = w.b.String()
+		w.m.Unlock()
+
+		if strings.Contains(out, "migrate:") || !strings.Contains(out, "api:end") {
+			t.Errorf("FAILED\n\texpected only (api) to re-run, in output:\n%s", out)
+		}
+	})
+}
+
+func Test_DAGExecutor_Invalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		nodes []DAGNode
+	}{
+		{
+			name:  "1. unknown dependency",
+			nodes: []DAGNode{{Name: "api", DependsOn: []string{"migrate"}}},
+		},
+		{
+			name: "2. cycle",
+			nodes: []DAGNode{
+				{Name: "a", DependsOn: []string{"c"}},
+				{Name: "b", DependsOn: []string{"a"}},
+				{Name: "c", DependsOn: []string{"b"}},
+			},
+		},
+		{
+			name:  "3. duplicate node",
+			nodes: []DAGNode{{Name: "api"}, {Name: "api"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewDAGExecutor(context.TODO(), DAGExecutorArgs{Nodes: tt.nodes}); err == nil {
+				t.Errorf("FAILED (%s), expected an error", tt.name)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package executor
+
+import (
+	"os"
+	"os/exec"
+	"slices"
+
+	"github.com/nxtcoder17/fwatcher/pkg/dotenv"
+)
+
+// env is the env (if any) of i-th command
+func (cg CommandGroup) env(i int) map[string]string {
+	if i < len(cg.Envs) {
+		return cg.Envs[i]
+	}
+	return nil
+}
+
+// isEnvFile tells whether path is one of the env files
+func isEnvFile(envFiles []string, path string) bool {
+	return len(envFiles) > 0 && slices.Contains(envFiles, absPath(path))
+}
+
+// loadEnvFiles (re)loads env files, on failure previously loaded values are kept, so that a half written env file
+// does not break running commands. It must be called with ex.mu held
+func (ex *CmdExecutor) loadEnvFiles() {
+	if len(ex.envFiles) == 0 {
+		return
+	}
+
+	env, err := dotenv.ParseFiles(ex.envFiles...)
+	if err != nil {
+		ex.logger.Error("[ENV FILE] failed to load, keeping previous values", "err", err)
+		return
+	}
+	ex.fileEnv = env
+}
+
+// withEnv sets env of cmd to its own env (or, the current process's env), overridden with fileEnv (values from env
+// files), and then with the command's own env
+func withEnv(cmd *exec.Cmd, fileEnv map[string]string, env map[string]string) {
+	if len(fileEnv) == 0 && len(env) == 0 {
+		return
+	}
+
+	base := cmd.Env
+	if base == nil {
+		base = os.Environ()
+	}
+
+	merged := make([]string, 0, len(base)+len(fileEnv)+len(env))
+	merged = append(merged, base...)
+	for k, v := range fileEnv {
+		merged = append(merged, k+"="+v)
+	}
+	for k, v := range env {
+		merged = append(merged, k+"="+v)
+	}
+
+	// INFO: exec.Cmd de-duplicates env, keeping the last value of a key
+	cmd.Env = merged
+}
@@ -0,0 +1,127 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nxtcoder17/go.pkgs/log"
+)
+
+func Test_Executor_Env(t *testing.T) {
+	b := new(bytes.Buffer)
+	w := Writer{b: b, m: sync.Mutex{}}
+
+	envFile := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(envFile, []byte("GREETING=hello\nNAME=world\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("FWATCHER_TEST_INHERITED", "inherited")
+
+	ctx, cf := context.WithCancel(context.TODO())
+	defer cf()
+
+	ex := NewCmdExecutor(ctx, CmdExecutorArgs{
+		Logger: log.New(log.Options{ShowDebugLogs: os.Getenv("DEBUG") == "true"}),
+		Commands: []CommandGroup{
+			{
+				Commands: []func(c context.Context) *exec.Cmd{
+					func(c context.Context) *exec.Cmd {
+						cmd := exec.CommandContext(c, "sh", "-c", `echo "$GREETING-$NAME-$FWATCHER_TEST_INHERITED"; sleep 5`)
+						cmd.Stdout = &w
+						cmd.Stderr = os.Stderr
+						return cmd
+					},
+				},
+				Envs:     []map[string]string{{"NAME": "fwatcher"}},
+				Patterns: []string{"*.go"},
+			},
+		},
+		EnvFiles: []string{envFile},
+	})
+
+	go ex.Start()
+	defer ex.Stop()
+
+	output := func() string {
+		<-time.After(300 * time.Millisecond)
+		w.m.Lock()
+		defer w.m.Unlock()
+		return strings.Join(strings.Fields(b.String()), ",")
+	}
+
+	// INFO: command's own env overrides env file, which overrides the process env
+	if got, want := output(), "hello-fwatcher-inherited"; got != want {
+		t.Fatalf("FAILED (initial run)\n\t got: %s\n\twant: %s\n", got, want)
+	}
+
+	if err := os.WriteFile(envFile, []byte("GREETING=hi\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// INFO: env file does not match the patterns, but still restarts the command
+	ex.OnWatchEvent(Event{Source: envFile})
+
+	if got, want := output(), "hello-fwatcher-inherited,hi-fwatcher-inherited"; got != want {
+		t.Fatalf("FAILED (env file changed)\n\t got: %s\n\twant: %s\n", got, want)
+	}
+
+	if err := os.WriteFile(envFile, []byte("GREETING=\"broken\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ex.OnWatchEvent(Event{Source: envFile})
+
+	// INFO: a broken env file, keeps the previously loaded values
+	if got, want := output(), "hello-fwatcher-inherited,hi-fwatcher-inherited,hi-fwatcher-inherited"; got != want {
+		t.Fatalf("FAILED (broken env file)\n\t got: %s\n\twant: %s\n", got, want)
+	}
+}
+
+func Test_Executor_Env_RapidRestarts(t *testing.T) {
+	envFile := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(envFile, []byte("GREETING=hello\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	ex := NewCmdExecutor(context.TODO(), CmdExecutorArgs{
+		Logger: log.New(log.Options{ShowDebugLogs: os.Getenv("DEBUG") == "true"}),
+		Commands: []CommandGroup{
+			{
+				Commands: []func(c context.Context) *exec.Cmd{
+					func(c context.Context) *exec.Cmd {
+						return exec.CommandContext(c, "sh", "-c", `sleep 5`)
+					},
+				},
+				// INFO: widens the window, between a command passing its context check, and reading env of env files
+				PreExecCommand: func(cmd *exec.Cmd) {
+					<-time.After(5 * time.Millisecond)
+				},
+			},
+		},
+		EnvFiles: []string{envFile},
+	})
+
+	go ex.Start()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range 100 {
+			ex.OnWatchEvent(Event{Source: envFile})
+			<-time.After(time.Millisecond)
+		}
+		ex.Stop()
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("FAILED, restarts got stuck (deadlocked)")
+	}
+}
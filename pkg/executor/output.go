@@ -0,0 +1,122 @@
+package executor
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// colors are ANSI colors, assigned to command names in a round-robin manner
+var colors = []string{"36", "33", "32", "35", "34", "96", "93", "92", "95", "94"}
+
+// OutputMux multiplexes output of multiple commands, line by line, prefixing every line with name of the command
+// it came from, like docker compose, or foreman do.
+type OutputMux struct {
+	mu sync.Mutex
+
+	noColor    bool
+	timestamps bool
+
+	// width is the width of the longest name seen so far, names are padded to it, to keep lines aligned
+	width  int
+	colors map[string]string
+}
+
+type OutputMuxArgs struct {
+	// NoColor disables colored prefixes, it is also disabled if NO_COLOR env var is set
+	NoColor bool
+
+	// Timestamps prefixes every line with time, at which it got written
+	Timestamps bool
+}
+
+func NewOutputMux(args OutputMuxArgs) *OutputMux {
+	return &OutputMux{
+		noColor:    args.NoColor || os.Getenv("NO_COLOR") != "",
+		timestamps: args.Timestamps,
+		colors:     map[string]string{},
+	}
+}
+
+// Writer returns a line buffered writer, that writes every line to w, prefixed with name.
+// It must be flushed, once command has exited, so that its last line (without a newline) is not lost
+func (m *OutputMux) Writer(name string, w io.Writer) *PrefixWriter {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.colors[name]; !ok {
+		m.colors[name] = colors[len(m.colors)%len(colors)]
+	}
+	m.width = max(m.width, utf8.RuneCountInString(name))
+
+	return &PrefixWriter{mux: m, name: name, w: w}
+}
+
+func (m *OutputMux) prefix(name string) string {
+	sb := strings.Builder{}
+	if m.timestamps {
+		sb.WriteString(time.Now().Format("15:04:05.000 "))
+	}
+
+	padded := fmt.Sprintf("%-*s |", m.width, name)
+	if m.noColor {
+		sb.WriteString(padded)
+	} else {
+		sb.WriteString(fmt.Sprintf("\033[%sm%s\033[0m", m.colors[name], padded))
+	}
+	sb.WriteString(" ")
+	return sb.String()
+}
+
+// PrefixWriter is a line buffered writer, for a single command's output
+type PrefixWriter struct {
+	mux  *OutputMux
+	name string
+	w    io.Writer
+
+	buf []byte
+}
+
+// Write implements io.Writer.
+func (pw *PrefixWriter) Write(b []byte) (int, error) {
+	pw.buf = append(pw.buf, b...)
+
+	for {
+		i := bytes.IndexByte(pw.buf, '\n')
+		if i < 0 {
+			break
+		}
+
+		if err := pw.writeLine(pw.buf[:i+1]); err != nil {
+			return 0, err
+		}
+		pw.buf = pw.buf[i+1:]
+	}
+
+	return len(b), nil
+}
+
+// Flush writes out the buffered partial line, if any
+func (pw *PrefixWriter) Flush() error {
+	if len(pw.buf) == 0 {
+		return nil
+	}
+
+	line := append(pw.buf, '\n')
+	pw.buf = nil
+	return pw.writeLine(line)
+}
+
+func (pw *PrefixWriter) writeLine(line []byte) error {
+	// INFO: a line is written as a whole, so that lines from different commands do not get interleaved
+	pw.mux.mu.Lock()
+	defer pw.mux.mu.Unlock()
+
+	_, err := pw.w.Write(append([]byte(pw.mux.prefix(pw.name)), line...))
+	return err
+}
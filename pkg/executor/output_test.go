@@ -0,0 +1,114 @@
+package executor
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func Test_OutputMux(t *testing.T) {
+	tests := []struct {
+		name    string
+		noColor bool
+		// writes are (command name, output) pairs, written in order
+		writes [][2]string
+		flush  bool
+		output []string
+	}{
+		{
+			name:    "1. lines are prefixed with padded names",
+			noColor: true,
+			writes: [][2]string{
+				{"web", "hi\n"},
+				{"worker", "hello\n"},
+			},
+			output: []string{
+				"web    | hi",
+				"worker | hello",
+			},
+		},
+		{
+			name:    "2. partial lines are buffered, until newline",
+			noColor: true,
+			writes: [][2]string{
+				{"web", "first "},
+				{"worker", "hello\n"},
+				{"web", "line\nsecond"},
+			},
+			output: []string{
+				"worker | hello",
+				"web    | first line",
+			},
+		},
+		{
+			name:    "3. partial lines are written on flush",
+			noColor: true,
+			writes: [][2]string{
+				{"web", "no newline"},
+			},
+			flush: true,
+			output: []string{
+				"web | no newline",
+			},
+		},
+		{
+			name: "4. with colors",
+			writes: [][2]string{
+				{"web", "hi\n"},
+				{"worker", "hello\n"},
+			},
+			output: []string{
+				fmt.Sprintf("\033[%sm%s\033[0m hi", colors[0], "web    |"),
+				fmt.Sprintf("\033[%sm%s\033[0m hello", colors[1], "worker |"),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("NO_COLOR", "")
+
+			b := new(bytes.Buffer)
+			mux := NewOutputMux(OutputMuxArgs{NoColor: tt.noColor})
+
+			writers := map[string]*PrefixWriter{}
+			for _, w := range tt.writes {
+				if _, ok := writers[w[0]]; !ok {
+					writers[w[0]] = mux.Writer(w[0], b)
+				}
+			}
+
+			for _, w := range tt.writes {
+				if _, err := writers[w[0]].Write([]byte(w[1])); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if tt.flush {
+				for _, w := range writers {
+					w.Flush()
+				}
+			}
+
+			want := strings.Join(tt.output, "\n")
+			got := strings.TrimSuffix(b.String(), "\n")
+
+			if got != want {
+				t.Errorf("FAILED (%s)\n\t got: %q\n\twant: %q\n", tt.name, got, want)
+			}
+		})
+	}
+}
+
+func Test_OutputMux_NoColorEnv(t *testing.T) {
+	t.Setenv("NO_COLOR", "1")
+
+	b := new(bytes.Buffer)
+	mux := NewOutputMux(OutputMuxArgs{})
+	mux.Writer("web", b).Write([]byte("hi\n"))
+
+	if got, want := b.String(), "web | hi\n"; got != want {
+		t.Errorf("FAILED\n\t got: %q\n\twant: %q\n", got, want)
+	}
+}
@@ -0,0 +1,57 @@
+package executor
+
+import (
+	"errors"
+	"sync"
+)
+
+// ParallelExecutor runs multiple executors concurrently, and forwards watch events to each of them
+type ParallelExecutor struct {
+	executors []Executor
+}
+
+func NewParallelExecutor(executors ...Executor) *ParallelExecutor {
+	return &ParallelExecutor{executors: executors}
+}
+
+// OnWatchEvent implements Executor.
+func (p *ParallelExecutor) OnWatchEvent(ev Event) error {
+	var errs []error
+	for i := range p.executors {
+		if err := p.executors[i].OnWatchEvent(ev); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Start implements Executor.
+// It starts every executor, and waits for all of them to finish
+func (p *ParallelExecutor) Start() error {
+	var wg sync.WaitGroup
+	errs := make([]error, len(p.executors))
+
+	for i := range p.executors {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = p.executors[i].Start()
+		}()
+	}
+
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// Stop implements Executor.
+func (p *ParallelExecutor) Stop() error {
+	var errs []error
+	for i := range p.executors {
+		if err := p.executors[i].Stop(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+var _ Executor = (*ParallelExecutor)(nil)
@@ -0,0 +1,262 @@
+package executor
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// slot is a command group, that runs concurrently with its siblings, and so can be restarted on its own
+type slot struct {
+	cancel  context.CancelFunc
+	restart bool
+}
+
+// slotRun is how a slot was run, so that it can be run again, once it has exited
+type slotRun struct {
+	ctx context.Context
+	fn  func(ctx context.Context) error
+}
+
+// slots are the restartable command groups of an execution, by their path in the commands tree (like 0/2)
+type slots struct {
+	mu   sync.Mutex
+	cond *sync.Cond
+	m    map[string]*slot
+	// runs are how slots (running, or exited) were run
+	runs map[string]slotRun
+	// rerunning is count of exited slots, that are being run again
+	rerunning int
+	// closed tells, that execution has finished, and so its exited slots can not be run again
+	closed bool
+}
+
+func newSlots() *slots {
+	s := &slots{m: map[string]*slot{}, runs: map[string]slotRun{}}
+	s.cond = sync.NewCond(&s.mu)
+	return s
+}
+
+// run runs fn in slot at key, and runs it again, every time that slot gets restarted
+func (s *slots) run(ctx context.Context, key string, fn func(ctx context.Context) error) error {
+	s.mu.Lock()
+	s.runs[key] = slotRun{ctx: ctx, fn: fn}
+	s.mu.Unlock()
+
+	for {
+		sctx, cf := context.WithCancel(ctx)
+		sl := &slot{cancel: cf}
+
+		s.mu.Lock()
+		s.m[key] = sl
+		s.mu.Unlock()
+
+		err := fn(sctx)
+		cf()
+
+		s.mu.Lock()
+		restart := sl.restart
+		if s.m[key] == sl {
+			delete(s.m, key)
+		}
+		s.mu.Unlock()
+
+		if !restart || ctx.Err() != nil {
+			return err
+		}
+	}
+}
+
+// restart restarts slots at keys, slots that have exited are run again on their own. It restarts nothing, and returns
+// false, if any of them can not be, i.e. execution (or, the group holding the slot) has finished
+func (s *slots) restart(keys ...string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range keys {
+		if _, ok := s.m[key]; ok {
+			continue
+		}
+		if r, ok := s.runs[key]; !ok || s.closed || r.ctx.Err() != nil {
+			return false
+		}
+	}
+
+	for _, key := range keys {
+		if sl, ok := s.m[key]; ok {
+			sl.restart = true
+			sl.cancel()
+			continue
+		}
+
+		// INFO: slot is marked running right away, so that it does not get run twice, on another restart meanwhile
+		r := s.runs[key]
+		s.m[key] = &slot{cancel: func() {}}
+		s.rerunning++
+		go func() {
+			s.run(r.ctx, key, r.fn)
+
+			s.mu.Lock()
+			s.rerunning--
+			s.cond.Broadcast()
+			s.mu.Unlock()
+		}()
+	}
+	return true
+}
+
+// wait waits for exited slots, that are being run again, and stops them from being run again, once execution has finished
+func (s *slots) wait() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for s.rerunning > 0 {
+		s.cond.Wait()
+	}
+	s.closed = true
+}
+
+func slotKey(parent string, i int) string {
+	if parent == "" {
+		return strconv.Itoa(i)
+	}
+	return parent + "/" + strconv.Itoa(i)
+}
+
+// matchPattern tells whether path matches pattern. A pattern is either a directory (or, dir/**), that matches everything
+// inside it, a glob matched against the whole path, or a glob without a path separator (like *.proto), matched against
+// the file name
+func matchPattern(pattern string, path string) bool {
+	if dir, ok := strings.CutSuffix(pattern, "/**"); ok {
+		return inDirs([]string{absPath(dir)}, path)
+	}
+
+	if !strings.ContainsAny(pattern, "*?[") {
+		return inDirs([]string{absPath(pattern)}, path)
+	}
+
+	if !strings.ContainsRune(pattern, filepath.Separator) {
+		ok, _ := filepath.Match(pattern, filepath.Base(path))
+		return ok
+	}
+
+	ok, _ := filepath.Match(absPath(pattern), absPath(path))
+	return ok
+}
+
+// matchPatterns tells whether path matches any of patterns, no patterns match every path
+func matchPatterns(patterns []string, path string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+
+	for _, pattern := range patterns {
+		if matchPattern(pattern, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasPatterns tells whether any group in groups (or, in their sub groups) has patterns
+func hasPatterns(groups []CommandGroup) bool {
+	for i := range groups {
+		if len(groups[i].Patterns) > 0 || len(groups[i].CommandPatterns) > 0 || hasPatterns(groups[i].Groups) {
+			return true
+		}
+	}
+	return false
+}
+
+// commandPatterns are the patterns of i-th command, along with patterns of the group
+func (cg CommandGroup) commandPatterns(i int) []string {
+	if i < len(cg.CommandPatterns) && len(cg.CommandPatterns[i]) > 0 {
+		return append(slices.Clip(cg.CommandPatterns[i]), cg.Patterns...)
+	}
+	return cg.Patterns
+}
+
+// expandCommandPatterns moves commands of parallel groups, that have patterns, into sub groups of their own, so that
+// they can be restarted on their own. A group, whose patterns restrict its sub groups too, is left as it is
+func expandCommandPatterns(groups []CommandGroup) []CommandGroup {
+	if len(groups) == 0 {
+		return groups
+	}
+
+	result := make([]CommandGroup, 0, len(groups))
+	for _, cg := range groups {
+		cg.Groups = expandCommandPatterns(cg.Groups)
+		if !cg.Parallel || len(cg.CommandPatterns) == 0 || (len(cg.Patterns) > 0 && len(cg.Groups) > 0) {
+			result = append(result, cg)
+			continue
+		}
+
+		sub := make([]CommandGroup, 0, len(cg.Commands)+len(cg.Groups))
+		for i := range cg.Commands {
+			sub = append(sub, CommandGroup{
+				Commands:         cg.Commands[i : i+1],
+				Names:            []string{cg.name(i)},
+				Envs:             []map[string]string{cg.env(i)},
+				Ports:            [][]int{cg.ports(i)},
+				ProcessAttrs:     cg.ProcessAttrs,
+				Patterns:         cg.commandPatterns(i),
+				PreExecCommand:   cg.PreExecCommand,
+				PostExecCommmand: cg.PostExecCommmand,
+			})
+		}
+		result = append(result, CommandGroup{Groups: append(sub, cg.Groups...), Parallel: true})
+	}
+	return result
+}
+
+// reacts tells whether a change to path, affects command group cg. A group with patterns, is affected only by paths
+// matching them (or, patterns of its commands), while a group without patterns is affected by every path, unless it
+// just holds sub groups, in which case it is affected, if any of them is
+func (cg CommandGroup) reacts(path string) bool {
+	for i := range cg.Commands {
+		if matchPatterns(cg.commandPatterns(i), path) {
+			return true
+		}
+	}
+
+	if len(cg.Patterns) > 0 {
+		return matchPatterns(cg.Patterns, path)
+	}
+
+	for i := range cg.Groups {
+		if cg.Groups[i].reacts(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// affectedSlots are the keys of smallest restartable groups, that need to be restarted, due to a change to path.
+// Groups can be restarted on their own, only when they run in parallel with their siblings, otherwise the closest
+// such ancestor needs to be restarted.
+func affectedSlots(key string, groups []CommandGroup, parallel bool, path string) []string {
+	var keys []string
+	for i := range groups {
+		cg := groups[i]
+		if !cg.reacts(path) {
+			continue
+		}
+
+		if !parallel {
+			// INFO: sequential siblings can not be restarted on their own, so the whole parent needs a restart
+			return []string{key}
+		}
+
+		k := slotKey(key, i)
+		if len(cg.Patterns) == 0 && len(cg.Commands) == 0 && cg.Parallel {
+			// INFO: group just holds parallel sub groups, so only the affected ones among them need a restart
+			keys = append(keys, affectedSlots(k, cg.Groups, true, path)...)
+			continue
+		}
+		keys = append(keys, k)
+	}
+	return keys
+}
@@ -0,0 +1,294 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nxtcoder17/go.pkgs/log"
+)
+
+func Test_matchPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{pattern: "services/api", path: "./services/api/main.go", want: true},
+		{pattern: "./services/api/**", path: "services/api/handlers/user.go", want: true},
+		{pattern: "services/api", path: "./services/api-gateway/main.go", want: false},
+		{pattern: "*.proto", path: "./proto/user/user.proto", want: true},
+		{pattern: "*.proto", path: "./proto/user/user.go", want: false},
+		{pattern: "services/*/go.mod", path: "./services/api/go.mod", want: true},
+		{pattern: "services/*/go.mod", path: "./services/api/main.go", want: false},
+		{pattern: "go.mod", path: "./go.mod", want: true},
+	}
+
+	for _, tt := range tests {
+		if got := matchPattern(tt.pattern, tt.path); got != tt.want {
+			t.Errorf("FAILED (pattern: %s, path: %s)\n\t got: %v\n\twant: %v\n", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func Test_affectedSlots(t *testing.T) {
+	services := []CommandGroup{
+		{Commands: []func(context.Context) *exec.Cmd{nil}, Patterns: []string{"services/api"}},
+		{Commands: []func(context.Context) *exec.Cmd{nil}, Patterns: []string{"services/worker"}},
+		{
+			Parallel: true,
+			Groups: []CommandGroup{
+				{Commands: []func(context.Context) *exec.Cmd{nil}, Patterns: []string{"services/mailer"}},
+				{Commands: []func(context.Context) *exec.Cmd{nil}, Patterns: []string{"services/billing"}},
+			},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		groups   []CommandGroup
+		parallel bool
+		path     string
+		want     []string
+	}{
+		{
+			name:     "1. only the matching group",
+			groups:   services,
+			parallel: true,
+			path:     "./services/worker/main.go",
+			want:     []string{"1"},
+		},
+		{
+			name:     "2. only the matching nested group",
+			groups:   services,
+			parallel: true,
+			path:     "./services/billing/main.go",
+			want:     []string{"2/1"},
+		},
+		{
+			name:     "3. no matching group",
+			groups:   services,
+			parallel: true,
+			path:     "./README.md",
+			want:     nil,
+		},
+		{
+			name:     "4. sequential groups restart as a whole",
+			groups:   services,
+			parallel: false,
+			path:     "./services/worker/main.go",
+			want:     []string{""},
+		},
+		{
+			name: "5. group without patterns, restarts on every change",
+			groups: append([]CommandGroup{
+				{Commands: []func(context.Context) *exec.Cmd{nil}},
+			}, services...),
+			parallel: true,
+			path:     "./services/api/main.go",
+			want:     []string{"0", "1"},
+		},
+		{
+			name: "6. only the matching command, of a parallel group",
+			groups: expandCommandPatterns([]CommandGroup{
+				{
+					Commands:        []func(context.Context) *exec.Cmd{nil, nil},
+					CommandPatterns: [][]string{{"services/api"}, {"services/worker"}},
+					Parallel:        true,
+				},
+			}),
+			parallel: true,
+			path:     "./services/worker/main.go",
+			want:     []string{"0/1"},
+		},
+		{
+			name: "7. commands matching patterns of their parallel group",
+			groups: expandCommandPatterns([]CommandGroup{
+				{
+					Commands:        []func(context.Context) *exec.Cmd{nil, nil},
+					CommandPatterns: [][]string{{"services/api"}, {"services/worker"}},
+					Patterns:        []string{"*.proto"},
+					Parallel:        true,
+				},
+			}),
+			parallel: true,
+			path:     "./proto/jobs.proto",
+			want:     []string{"0/0", "0/1"},
+		},
+		{
+			name: "8. sequential group restarts as a whole, on a change matching any of its commands",
+			groups: expandCommandPatterns([]CommandGroup{
+				{
+					Commands:        []func(context.Context) *exec.Cmd{nil, nil},
+					CommandPatterns: [][]string{{"services/api"}, {"services/worker"}},
+				},
+				{Commands: []func(context.Context) *exec.Cmd{nil}, Patterns: []string{"services/mailer"}},
+			}),
+			parallel: true,
+			path:     "./services/worker/main.go",
+			want:     []string{"0"},
+		},
+		{
+			name: "9. no matching command",
+			groups: expandCommandPatterns([]CommandGroup{
+				{
+					Commands:        []func(context.Context) *exec.Cmd{nil, nil},
+					CommandPatterns: [][]string{{"services/api"}, {"services/worker"}},
+				},
+			}),
+			parallel: true,
+			path:     "./README.md",
+			want:     nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := affectedSlots("", tt.groups, tt.parallel, tt.path)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FAILED (%s)\n\t got: %v\n\twant: %v\n", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_Executor_IncrementalRestart(t *testing.T) {
+	b := new(bytes.Buffer)
+	w := Writer{b: b, m: sync.Mutex{}}
+
+	service := func(name string) CommandGroup {
+		return CommandGroup{
+			Commands: []func(c context.Context) *exec.Cmd{
+				func(c context.Context) *exec.Cmd {
+					cmd := exec.CommandContext(c, "sh", "-c", "echo "+name+"; sleep 5")
+					cmd.Stdout = &w
+					return cmd
+				},
+			},
+			Patterns: []string{"services/" + name},
+		}
+	}
+
+	ex := NewCmdExecutor(context.TODO(), CmdExecutorArgs{
+		Logger:   log.New(log.Options{ShowDebugLogs: os.Getenv("DEBUG") == "true"}),
+		Commands: []CommandGroup{service("api"), service("worker"), service("mailer")},
+		Parallel: true,
+	})
+	defer ex.Stop()
+
+	go ex.Start()
+	<-time.After(300 * time.Millisecond)
+
+	ex.OnWatchEvent(Event{Source: "./services/worker/main.go"})
+	<-time.After(300 * time.Millisecond)
+
+	ex.OnWatchEvent(Event{Source: "./README.md"})
+	<-time.After(300 * time.Millisecond)
+
+	w.m.Lock()
+	out := strings.Fields(b.String())
+	w.m.Unlock()
+
+	count := map[string]int{}
+	for _, name := range out {
+		count[name]++
+	}
+
+	if want := map[string]int{"api": 1, "worker": 2, "mailer": 1}; !reflect.DeepEqual(count, want) {
+		t.Errorf("FAILED\n\t got: %v\n\twant: %v\n", count, want)
+	}
+}
+
+func Test_Executor_IncrementalRestart_CommandPatterns(t *testing.T) {
+	b := new(bytes.Buffer)
+	w := Writer{b: b, m: sync.Mutex{}}
+
+	ex := NewCmdExecutor(context.TODO(), CmdExecutorArgs{
+		Logger: log.New(log.Options{ShowDebugLogs: os.Getenv("DEBUG") == "true"}),
+		Commands: []CommandGroup{
+			{
+				Commands: []func(c context.Context) *exec.Cmd{
+					shCmd(&w, "echo api; sleep 5"),
+					shCmd(&w, "echo worker; sleep 5"),
+					shCmd(&w, "echo mailer; sleep 5"),
+				},
+				CommandPatterns: [][]string{{"services/api"}, {"services/worker"}, {"services/mailer"}},
+				Parallel:        true,
+			},
+		},
+		Parallel: true,
+	})
+	defer ex.Stop()
+
+	go ex.Start()
+	<-time.After(300 * time.Millisecond)
+
+	ex.OnWatchEvent(Event{Source: "./services/worker/main.go"})
+	<-time.After(300 * time.Millisecond)
+
+	ex.OnWatchEvent(Event{Source: "./README.md"})
+	<-time.After(300 * time.Millisecond)
+
+	w.m.Lock()
+	out := b.String()
+	w.m.Unlock()
+
+	got := map[string]int{"api": count(out, "api"), "worker": count(out, "worker"), "mailer": count(out, "mailer")}
+	if want := map[string]int{"api": 1, "worker": 2, "mailer": 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("FAILED\n\t got: %v\n\twant: %v\n", got, want)
+	}
+}
+
+func Test_Executor_IncrementalRestart_ExitedService(t *testing.T) {
+	b := new(bytes.Buffer)
+	w := Writer{b: b, m: sync.Mutex{}}
+
+	service := func(name string, script string) CommandGroup {
+		return CommandGroup{
+			Commands: []func(c context.Context) *exec.Cmd{
+				func(c context.Context) *exec.Cmd {
+					cmd := exec.CommandContext(c, "sh", "-c", "echo "+name+"; "+script)
+					cmd.Stdout = &w
+					return cmd
+				},
+			},
+			Patterns: []string{"services/" + name},
+		}
+	}
+
+	ex := NewCmdExecutor(context.TODO(), CmdExecutorArgs{
+		Logger:   log.New(log.Options{ShowDebugLogs: os.Getenv("DEBUG") == "true"}),
+		Commands: []CommandGroup{service("api", "sleep 5"), service("worker", "exit 1"), service("mailer", "sleep 5")},
+		Parallel: true,
+	})
+	defer ex.Stop()
+
+	go ex.Start()
+	<-time.After(300 * time.Millisecond)
+
+	// INFO: worker has crashed by now, fixing it must re-run just the worker
+	ex.OnWatchEvent(Event{Source: "./services/worker/main.go"})
+	<-time.After(300 * time.Millisecond)
+
+	ex.OnWatchEvent(Event{Source: "./services/worker/main.go"})
+	<-time.After(300 * time.Millisecond)
+
+	w.m.Lock()
+	out := strings.Fields(b.String())
+	w.m.Unlock()
+
+	count := map[string]int{}
+	for _, name := range out {
+		count[name]++
+	}
+
+	if want := map[string]int{"api": 1, "worker": 3, "mailer": 1}; !reflect.DeepEqual(count, want) {
+		t.Errorf("FAILED\n\t got: %v\n\twant: %v\n", count, want)
+	}
+}
@@ -0,0 +1,70 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"strconv"
+	"time"
+)
+
+// ErrPortInUse is returned, when a port that command binds, is still in use, after waiting for it to be released
+var ErrPortInUse = errors.New("port is still in use")
+
+// DefaultPortTimeout is how long commands wait for their ports to be released, by default
+const DefaultPortTimeout = 5 * time.Second
+
+// portPollInterval is the interval, at which ports are checked for being released
+var portPollInterval = 50 * time.Millisecond
+
+// ports are the ports (if any) that i-th command binds
+func (cg CommandGroup) ports(i int) []int {
+	if i < len(cg.Ports) {
+		return cg.Ports[i]
+	}
+	return nil
+}
+
+// portFree tells whether port can be bound (on all interfaces)
+func portFree(port int) bool {
+	l, err := net.Listen("tcp", ":"+strconv.Itoa(port))
+	if err != nil {
+		return false
+	}
+	l.Close()
+	return true
+}
+
+// waitForPorts waits for ports to be released (like, by the previous instance of command), for up to timeout
+func waitForPorts(ctx context.Context, ports []int, timeout time.Duration, logger *slog.Logger) error {
+	deadline := time.Now().Add(timeout)
+
+	ticker := time.NewTicker(portPollInterval)
+	defer ticker.Stop()
+
+	for _, port := range ports {
+		if portFree(port) {
+			continue
+		}
+
+		logger.Debug("waiting for port to be released", "port", port)
+		for !portFree(port) {
+			if time.Now().After(deadline) {
+				if pid, name, ok := portHolder(port); ok {
+					return fmt.Errorf("%w: %d, held by pid %d (%s), even after waiting for %s", ErrPortInUse, port, pid, name, timeout)
+				}
+				return fmt.Errorf("%w: %d, even after waiting for %s", ErrPortInUse, port, timeout)
+			}
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-ticker.C:
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,80 @@
+package executor
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// tcpListen is the state of a listening socket, in /proc/net/tcp
+const tcpListen = "0A"
+
+// portHolder finds the process, that listens on tcp port, from /proc/net/tcp{,6}, and sockets in /proc/<pid>/fd
+func portHolder(port int) (pid int, name string, ok bool) {
+	inodes := map[string]struct{}{}
+	for _, file := range []string{"/proc/net/tcp", "/proc/net/tcp6"} {
+		for _, inode := range listeningInodes(file, port) {
+			inodes["socket:["+inode+"]"] = struct{}{}
+		}
+	}
+
+	if len(inodes) == 0 {
+		return 0, "", false
+	}
+
+	for _, p := range processes() {
+		fds, err := os.ReadDir(fmt.Sprintf("/proc/%d/fd", p.pid))
+		if err != nil {
+			continue
+		}
+
+		for _, fd := range fds {
+			link, err := os.Readlink(filepath.Join(fmt.Sprintf("/proc/%d/fd", p.pid), fd.Name()))
+			if err != nil {
+				continue
+			}
+
+			if _, ok := inodes[link]; ok {
+				comm, _ := os.ReadFile(fmt.Sprintf("/proc/%d/comm", p.pid))
+				return p.pid, strings.TrimSpace(string(comm)), true
+			}
+		}
+	}
+
+	return 0, "", false
+}
+
+// listeningInodes are inodes of sockets listening on port, as per file (like /proc/net/tcp)
+func listeningInodes(file string, port int) []string {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var inodes []string
+
+	scanner := bufio.NewScanner(f)
+	scanner.Scan() // INFO: skipping header
+	for scanner.Scan() {
+		// INFO: line looks like, sl local_address rem_address st tx_queue:rx_queue tr:tm->when retrnsmt uid timeout inode ...
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 || fields[3] != tcpListen {
+			continue
+		}
+
+		_, hexPort, ok := strings.Cut(fields[1], ":")
+		if !ok {
+			continue
+		}
+
+		if p, err := strconv.ParseUint(hexPort, 16, 16); err == nil && int(p) == port {
+			inodes = append(inodes, fields[9])
+		}
+	}
+
+	return inodes
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package executor
+
+// portHolder finds the process, that listens on tcp port, it is only supported on linux
+func portHolder(port int) (pid int, name string, ok bool) {
+	return 0, "", false
+}
@@ -0,0 +1,103 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nxtcoder17/go.pkgs/log"
+)
+
+// listen listens on a free tcp port (on all interfaces)
+func listen(t *testing.T) (net.Listener, int) {
+	l, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l, l.Addr().(*net.TCPAddr).Port
+}
+
+func Test_WaitForPorts(t *testing.T) {
+	logger := log.New(log.Options{ShowDebugLogs: os.Getenv("DEBUG") == "true"})
+
+	t.Run("1. port gets released", func(t *testing.T) {
+		l, port := listen(t)
+		go func() {
+			<-time.After(200 * time.Millisecond)
+			l.Close()
+		}()
+
+		start := time.Now()
+		if err := waitForPorts(context.TODO(), []int{port}, time.Second, logger); err != nil {
+			t.Fatalf("FAILED (port gets released)\n\t got: %v\n\twant: nil\n", err)
+		}
+
+		if took := time.Since(start); took < 200*time.Millisecond {
+			t.Errorf("FAILED (port gets released), did not wait for it\n\t got: %s\n\twant: >= 200ms\n", took)
+		}
+	})
+
+	t.Run("2. port stays in use", func(t *testing.T) {
+		l, port := listen(t)
+		defer l.Close()
+
+		err := waitForPorts(context.TODO(), []int{port}, 200*time.Millisecond, logger)
+		if !errors.Is(err, ErrPortInUse) {
+			t.Fatalf("FAILED (port stays in use)\n\t got: %v\n\twant: %v\n", err, ErrPortInUse)
+		}
+
+		// INFO: holder of the port is only known on linux
+		if want := fmt.Sprintf("held by pid %d", os.Getpid()); runtime.GOOS == "linux" && !strings.Contains(err.Error(), want) {
+			t.Errorf("FAILED (port stays in use), error does not name the holder\n\t got: %s\n\twant: %s\n", err, want)
+		}
+	})
+
+	t.Run("3. cancelled while waiting", func(t *testing.T) {
+		l, port := listen(t)
+		defer l.Close()
+
+		ctx, cf := context.WithTimeout(context.TODO(), 100*time.Millisecond)
+		defer cf()
+
+		if err := waitForPorts(ctx, []int{port}, time.Second, logger); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("FAILED (cancelled while waiting)\n\t got: %v\n\twant: %v\n", err, context.DeadlineExceeded)
+		}
+	})
+}
+
+func Test_Executor_Ports(t *testing.T) {
+	l, port := listen(t)
+	defer l.Close()
+
+	started := false
+	ex := NewCmdExecutor(context.TODO(), CmdExecutorArgs{
+		Logger: log.New(log.Options{ShowDebugLogs: os.Getenv("DEBUG") == "true"}),
+		Commands: []CommandGroup{
+			{
+				Commands: []func(c context.Context) *exec.Cmd{
+					func(c context.Context) *exec.Cmd {
+						started = true
+						return exec.CommandContext(c, "true")
+					},
+				},
+				Ports: [][]int{{port}},
+			},
+		},
+		PortTimeout: 200 * time.Millisecond,
+	})
+
+	if err := ex.Start(); !errors.Is(err, ErrPortInUse) {
+		t.Errorf("FAILED\n\t got: %v\n\twant: %v\n", err, ErrPortInUse)
+	}
+
+	if started {
+		t.Errorf("FAILED, command got started, while its port is in use")
+	}
+}
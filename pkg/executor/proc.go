@@ -0,0 +1,316 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// ProcessAttrs are attributes of the processes, that commands are run as
+type ProcessAttrs struct {
+	// Dir is the working directory, it defaults to that of fwatcher (unless command sets its own)
+	Dir string
+
+	// User (name, or uid) to run as, it needs fwatcher to be running with enough privileges
+	User string
+	// Group (name, or gid) to run as, it defaults to the primary group of User
+	Group string
+
+	// Umask (if not nil) is the file mode creation mask, it needs RunShim
+	Umask *int
+
+	// Limits are resource limits, they need RunShim
+
+	Limits Limits
+}
+
+// Limits are resource limits (rlimits) of a process, zero values mean no limit (i.e. inherited from fwatcher)
+type Limits struct {
+	// OpenFiles limits number of open file descriptors (RLIMIT_NOFILE)
+	OpenFiles uint64
+	// Memory limits size of virtual address space, in bytes (RLIMIT_AS)
+	Memory uint64
+	// CPUTime limits cpu time, the process gets killed with SIGXCPU (and then SIGKILL) once it exceeds it (RLIMIT_CPU)
+	CPUTime time.Duration
+}
+
+// merge overrides attrs with non-zero attributes of other
+func (attrs ProcessAttrs) merge(other *ProcessAttrs) ProcessAttrs {
+	if other == nil {
+		return attrs
+	}
+
+	if other.Dir != "" {
+		attrs.Dir = other.Dir
+	}
+	if other.User != "" {
+		attrs.User = other.User
+		attrs.Group = other.Group
+	}
+	if other.Group != "" {
+		attrs.Group = other.Group
+	}
+	if other.Umask != nil {
+		attrs.Umask = other.Umask
+	}
+	if other.Limits.OpenFiles > 0 {
+		attrs.Limits.OpenFiles = other.Limits.OpenFiles
+	}
+	if other.Limits.Memory > 0 {
+		attrs.Limits.Memory = other.Limits.Memory
+	}
+	if other.Limits.CPUTime > 0 {
+		attrs.Limits.CPUTime = other.Limits.CPUTime
+	}
+	return attrs
+}
+
+// credential resolves User, and Group into a credential, it is nil if neither is set
+func (attrs ProcessAttrs) credential() (*syscall.Credential, error) {
+	if attrs.User == "" && attrs.Group == "" {
+		return nil, nil
+	}
+
+	cred := &syscall.Credential{Uid: uint32(syscall.Getuid()), Gid: uint32(syscall.Getgid())}
+
+	if attrs.User != "" {
+		u, err := lookupUser(attrs.User)
+		if err != nil {
+			return nil, err
+		}
+
+		uid, _ := strconv.ParseUint(u.Uid, 10, 32)
+		gid, _ := strconv.ParseUint(u.Gid, 10, 32)
+		cred.Uid, cred.Gid = uint32(uid), uint32(gid)
+	}
+
+	if attrs.Group != "" {
+		gid, err := lookupGroup(attrs.Group)
+		if err != nil {
+			return nil, err
+		}
+		cred.Gid = gid
+	}
+
+	return cred, nil
+}
+
+func lookupUser(name string) (*user.User, error) {
+	if _, err := strconv.ParseUint(name, 10, 32); err == nil {
+		if u, err := user.LookupId(name); err == nil {
+			return u, nil
+		}
+		// INFO: a uid without a passwd entry, runs with the current gid
+		return &user.User{Uid: name, Gid: strconv.Itoa(syscall.Getgid())}, nil
+	}
+
+	u, err := user.Lookup(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown user (%s): %w", name, err)
+	}
+	return u, nil
+}
+
+func lookupGroup(name string) (uint32, error) {
+	if gid, err := strconv.ParseUint(name, 10, 32); err == nil {
+		return uint32(gid), nil
+	}
+
+	g, err := user.LookupGroup(name)
+	if err != nil {
+		return 0, fmt.Errorf("unknown group (%s): %w", name, err)
+	}
+	gid, _ := strconv.ParseUint(g.Gid, 10, 32)
+	return uint32(gid), nil
+}
+
+// apply sets working directory, and credentials of cmd
+func (attrs ProcessAttrs) apply(cmd *exec.Cmd) error {
+	if attrs.Dir != "" && cmd.Dir == "" {
+		cmd.Dir = attrs.Dir
+	}
+
+	cred, err := attrs.credential()
+	if err != nil {
+		return err
+	}
+	if cred != nil {
+		cmd.SysProcAttr.Credential = cred
+	}
+	return nil
+}
+
+const (
+	// limitsEnv carries Limits of a command (as comma separated ParseLimit values), into its shim
+	limitsEnv = "FWATCHER_LIMITS"
+	// umaskEnv carries Umask of a command (in octal), into its shim
+	umaskEnv = "FWATCHER_UMASK"
+	// shimExecEnv carries path of the command, that shim execs
+	shimExecEnv = "FWATCHER_SHIM_EXEC"
+)
+
+// shimEnabled tells whether RunShim has been called, i.e. whether the current executable can act as a shim
+var shimEnabled atomic.Bool
+
+/*
+RunShim must be called at the start of main, by programs that run commands with Umask, or Limits.
+
+exec.Cmd can not set umask, or rlimits of the child, and setting them on the current process (to be inherited on fork),
+would affect all of its goroutines too. So, such commands are started via a shim instead, i.e. the program's own
+executable, that sets them on itself, and then execs the command. RunShim never returns, in such a shim.
+*/
+func RunShim() {
+	if path, ok := os.LookupEnv(shimExecEnv); ok {
+		runShim(path)
+	}
+	shimEnabled.Store(true)
+}
+
+// shim makes cmd start via the shim, so that it starts with Umask, and Limits in place, before it execs
+func (attrs ProcessAttrs) shim(cmd *exec.Cmd) error {
+	if attrs.Umask == nil && attrs.Limits == (Limits{}) {
+		return nil
+	}
+
+	if !shimEnabled.Load() {
+		return errors.New("umask, and resource limits of commands need executor.RunShim() to be called at the start of main")
+	}
+
+	if err := checkLimits(attrs.Limits); err != nil {
+		return err
+	}
+
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+
+	if spec := attrs.Limits.spec(); spec != "" {
+		cmd.Env = append(cmd.Env, limitsEnv+"="+spec)
+	}
+	if attrs.Umask != nil {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%04o", umaskEnv, *attrs.Umask))
+	}
+	cmd.Env = append(cmd.Env, shimExecEnv+"="+cmd.Path)
+
+	path, err := selfExecutable()
+	if err != nil {
+		return err
+	}
+	cmd.Path = path
+	return nil
+}
+
+// spec is limits, as comma separated ParseLimit values
+func (limits Limits) spec() string {
+	var spec []string
+	if limits.OpenFiles > 0 {
+		spec = append(spec, fmt.Sprintf("nofile=%d", limits.OpenFiles))
+	}
+	if limits.Memory > 0 {
+		spec = append(spec, fmt.Sprintf("as=%d", limits.Memory))
+	}
+	if limits.CPUTime > 0 {
+		spec = append(spec, "cpu="+limits.CPUTime.String())
+	}
+	return strings.Join(spec, ",")
+}
+
+// runShim sets umask, and limits (from env) on the current process, and execs the command at path, with the args it
+// was started with
+func runShim(path string) {
+	umask, hasUmask := os.LookupEnv(umaskEnv)
+	spec := os.Getenv(limitsEnv)
+	for _, key := range []string{limitsEnv, umaskEnv, shimExecEnv} {
+		os.Unsetenv(key)
+	}
+
+	fail := func(err error) {
+		fmt.Fprintf(os.Stderr, "fwatcher: failed to run %s with umask, or resource limits: %s\n", path, err)
+		os.Exit(126)
+	}
+
+	if hasUmask {
+		mask, err := strconv.ParseUint(umask, 8, 32)
+		if err != nil {
+			fail(fmt.Errorf("invalid umask (%s)", umask))
+		}
+		syscall.Umask(int(mask))
+	}
+
+	if spec != "" {
+		var limits Limits
+		for _, s := range strings.Split(spec, ",") {
+			if err := ParseLimit(s, &limits); err != nil {
+				fail(err)
+			}
+		}
+
+		if err := setLimits(limits); err != nil {
+			fail(err)
+		}
+	}
+
+	fail(syscall.Exec(path, os.Args, os.Environ()))
+}
+
+// ParseLimit parses a limit, of form <nofile|as|cpu>=<value>, into limits. Memory (as) takes sizes like 512M, or 2G, and
+// cpu takes durations like 30s
+func ParseLimit(s string, limits *Limits) error {
+	name, value, ok := strings.Cut(s, "=")
+	if !ok {
+		return fmt.Errorf("invalid limit (%s), must be of form <nofile|as|cpu>=<value>", s)
+	}
+
+	switch name {
+	case "nofile":
+		n, err := strconv.ParseUint(value, 10, 64)
+		if err != nil || n == 0 {
+			return fmt.Errorf("invalid nofile limit (%s), must be a positive number", value)
+		}
+		limits.OpenFiles = n
+	case "as":
+		n, err := ParseSize(value)
+		if err != nil {
+			return fmt.Errorf("invalid as limit (%s): %w", value, err)
+		}
+		limits.Memory = n
+	case "cpu":
+		d, err := time.ParseDuration(value)
+		if err != nil || d < time.Second {
+			return fmt.Errorf("invalid cpu limit (%s), must be a duration of at least 1s", value)
+		}
+		limits.CPUTime = d
+	default:
+		return fmt.Errorf("unknown limit (%s), must be one of nofile, as or cpu", name)
+	}
+	return nil
+}
+
+// ParseSize parses sizes like 1024, 512K, 256M, or 2G into bytes
+func ParseSize(s string) (uint64, error) {
+	multiplier := uint64(1)
+	switch {
+	case strings.HasSuffix(s, "K"):
+		multiplier = 1 << 10
+	case strings.HasSuffix(s, "M"):
+		multiplier = 1 << 20
+	case strings.HasSuffix(s, "G"):
+		multiplier = 1 << 30
+	}
+	if multiplier > 1 {
+		s = s[:len(s)-1]
+	}
+
+	n, err := strconv.ParseUint(s, 10, 64)
+	if err != nil || n == 0 {
+		return 0, fmt.Errorf("must be a positive size, like 512M, or 2G")
+	}
+	return n * multiplier, nil
+}
@@ -0,0 +1,50 @@
+package executor
+
+import (
+	"fmt"
+	"syscall"
+	"time"
+)
+
+// selfExecutable is the path, the current executable gets run with, as a shim
+func selfExecutable() (string, error) {
+	// INFO: /proc/self/exe of the forked child, is the current executable, even if it has been replaced (or, removed) since
+	return "/proc/self/exe", nil
+}
+
+// checkLimits tells whether limits can be set, on this platform
+func checkLimits(limits Limits) error {
+	return nil
+}
+
+// setLimits sets limits on the current process
+func setLimits(limits Limits) error {
+	rlimits := []struct {
+		resource int
+		value    uint64
+	}{
+		{resource: syscall.RLIMIT_NOFILE, value: limits.OpenFiles},
+		{resource: syscall.RLIMIT_CPU, value: uint64(limits.CPUTime.Round(time.Second) / time.Second)},
+		// INFO: address space is limited last, so that it does not get in the way of the shim itself
+		{resource: syscall.RLIMIT_AS, value: limits.Memory},
+	}
+
+	for _, l := range rlimits {
+		if l.value == 0 {
+			continue
+		}
+
+		rlimit := syscall.Rlimit{Cur: l.value, Max: l.value}
+		if l.resource == syscall.RLIMIT_CPU {
+			// INFO: SIGXCPU on soft limit, and SIGKILL a second later, if process ignores it
+			rlimit.Max = l.value + 1
+		}
+
+		// INFO: syscall.Setrlimit (unlike prlimit) also stops syscall.Exec from restoring the nofile limit, that go
+		// runtime raised on start
+		if err := syscall.Setrlimit(l.resource, &rlimit); err != nil {
+			return fmt.Errorf("failed to set rlimit (%d) to %d: %w", l.resource, l.value, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+//go:build !linux
+
+package executor
+
+import (
+	"fmt"
+	"os"
+)
+
+// selfExecutable is the path, the current executable gets run with, as a shim
+func selfExecutable() (string, error) {
+	return os.Executable()
+}
+
+// checkLimits tells whether limits can be set, on this platform, which is only linux
+func checkLimits(limits Limits) error {
+	if limits != (Limits{}) {
+		return fmt.Errorf("resource limits are only supported on linux")
+	}
+	return nil
+}
+
+// setLimits sets limits on the current process, which is only supported on linux
+func setLimits(limits Limits) error {
+	return checkLimits(limits)
+}
@@ -0,0 +1,100 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/nxtcoder17/go.pkgs/log"
+)
+
+func TestMain(m *testing.M) {
+	RunShim()
+	os.Exit(m.Run())
+}
+
+func Test_ParseLimit(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Limits
+		wantErr bool
+	}{
+		{input: "nofile=256", want: Limits{OpenFiles: 256}},
+		{input: "as=512M", want: Limits{Memory: 512 << 20}},
+		{input: "as=2G", want: Limits{Memory: 2 << 30}},
+		{input: "as=4096", want: Limits{Memory: 4096}},
+		{input: "cpu=30s", want: Limits{CPUTime: 30 * time.Second}},
+		{input: "cpu=10ms", wantErr: true},
+		{input: "nofile=0", wantErr: true},
+		{input: "as=lots", wantErr: true},
+		{input: "stack=8M", wantErr: true},
+		{input: "nofile", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			var got Limits
+			err := ParseLimit(tt.input, &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FAILED (%s)\n\t got err: %v\n\twant err: %v\n", tt.input, err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("FAILED (%s)\n\t got: %+v\n\twant: %+v\n", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_Executor_ProcessAttrs(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("resource limits are only supported on linux")
+	}
+
+	b := new(bytes.Buffer)
+	w := Writer{b: b, m: sync.Mutex{}}
+
+	dir := t.TempDir()
+	umask := 0o027
+
+	prevUmask := syscall.Umask(0o022)
+	syscall.Umask(prevUmask)
+
+	ex := NewCmdExecutor(context.TODO(), CmdExecutorArgs{
+		Logger: log.New(log.Options{ShowDebugLogs: os.Getenv("DEBUG") == "true"}),
+		Commands: []CommandGroup{
+			{
+				Commands:     []func(c context.Context) *exec.Cmd{shCmd(&w, "pwd; umask; ulimit -n; ulimit -Hn; id -u; echo ${FWATCHER_LIMITS:-none}")},
+				ProcessAttrs: &ProcessAttrs{Limits: Limits{OpenFiles: 64}},
+			},
+		},
+		ProcessAttrs: ProcessAttrs{
+			Dir:    dir,
+			User:   strconv.Itoa(os.Getuid()),
+			Umask:  &umask,
+			Limits: Limits{OpenFiles: 128},
+		},
+	})
+
+	if err := ex.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	// INFO: group's limit overrides that of executor, while rest of the attributes come from executor
+	if got, want := strings.Join(strings.Fields(b.String()), ","), strings.Join([]string{dir, "0027", "64", "64", strconv.Itoa(os.Getuid()), "none"}, ","); got != want {
+		t.Errorf("FAILED\n\t got: %s\n\twant: %s\n", got, want)
+	}
+
+	// INFO: umask is set in the shim, never in the current process
+	if umask := syscall.Umask(prevUmask); umask != prevUmask {
+		t.Errorf("FAILED, umask of the current process\n\t got: %04o\n\twant: %04o\n", umask, prevUmask)
+	}
+}
@@ -0,0 +1,173 @@
+package executor
+
+import (
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/creack/pty"
+)
+
+// defaultPTYSize is the size of a pty, when fwatcher itself is not attached to a terminal
+var defaultPTYSize = pty.Winsize{Rows: 24, Cols: 80}
+
+// ptyDrainTimeout is how long output of a pty is drained, after its process has exited. Processes left behind by the
+// command, might still be holding the pty open
+var ptyDrainTimeout = 200 * time.Millisecond
+
+// ptys are the ptys of running commands, they are resized as fwatcher's terminal gets resized
+var ptys = struct {
+	sync.Mutex
+	m map[*os.File]struct{}
+}{m: map[*os.File]struct{}{}}
+
+var watchResize = sync.OnceFunc(func() {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGWINCH)
+
+	go func() {
+		for range sigs {
+			ptys.Lock()
+			for ptmx := range ptys.m {
+				resizePTY(ptmx)
+			}
+			ptys.Unlock()
+		}
+	}()
+})
+
+// resizePTY resizes ptmx to the size of fwatcher's terminal, if it is attached to one
+func resizePTY(ptmx *os.File) {
+	for _, f := range []*os.File{os.Stdin, os.Stdout, os.Stderr} {
+		if size, err := pty.GetsizeFull(f); err == nil {
+			pty.Setsize(ptmx, size)
+			return
+		}
+	}
+	pty.Setsize(ptmx, &defaultPTYSize)
+}
+
+// ptyProcess is a command attached to a pty
+type ptyProcess struct {
+	ptmx   *os.File
+	tty    *os.File
+	copied chan struct{}
+}
+
+// attachPTY attaches cmd to a new pty, i.e. its stdin, stdout and stderr (merged into stdout), and makes it its
+// controlling terminal. Output of pty is copied to cmd's stdout, once it starts
+func attachPTY(cmd *exec.Cmd) (*ptyProcess, error) {
+	watchResize()
+
+	ptmx, tty, err := pty.Open()
+	if err != nil {
+		return nil, err
+	}
+	resizePTY(ptmx)
+
+	out := cmd.Stdout
+	if out == nil {
+		out = io.Discard
+	}
+
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = tty, tty, tty
+
+	// INFO: a controlling terminal needs a session of its own, which also makes the process a process group leader
+	cmd.SysProcAttr.Setpgid = false
+	cmd.SysProcAttr.Foreground = false
+	cmd.SysProcAttr.Setsid = true
+	cmd.SysProcAttr.Setctty = true
+	cmd.SysProcAttr.Ctty = 0
+
+	p := &ptyProcess{ptmx: ptmx, tty: tty, copied: make(chan struct{})}
+
+	ptys.Lock()
+	ptys.m[ptmx] = struct{}{}
+	ptys.Unlock()
+
+	go func() {
+		defer close(p.copied)
+		crlf := &crlfWriter{w: out}
+		// INFO: reading pty fails (with EIO, on linux), once every process holding it has exited, or once it is closed
+		io.Copy(crlf, ptmx)
+		crlf.Flush()
+	}()
+
+	return p, nil
+}
+
+// started closes the parent's copy of the terminal, that only the process needs now
+func (p *ptyProcess) started() {
+	p.tty.Close()
+}
+
+// close waits for the remaining output of pty (for up to ptyDrainTimeout), and closes it
+func (p *ptyProcess) close() error {
+	ptys.Lock()
+	delete(ptys.m, p.ptmx)
+	ptys.Unlock()
+
+	// INFO: closing tty again, in case process did not start
+	p.tty.Close()
+
+	select {
+	case <-p.copied:
+	case <-time.After(ptyDrainTimeout):
+	}
+
+	err := p.ptmx.Close()
+	<-p.copied
+	if errors.Is(err, os.ErrClosed) {
+		return nil
+	}
+	return err
+}
+
+// crlfWriter translates \r\n (that pty translates \n into) back into \n, as fwatcher's own terminal (if any) does that
+// translation anyway. A lone \r (like, of a progress bar) is kept as is
+type crlfWriter struct {
+	w io.Writer
+	// cr tells, that last write ended with \r
+	cr bool
+}
+
+func (cw *crlfWriter) Write(b []byte) (int, error) {
+	out := make([]byte, 0, len(b)+1)
+	if cw.cr && (len(b) == 0 || b[0] != '\n') {
+		out = append(out, '\r')
+	}
+	cw.cr = false
+
+	for i, c := range b {
+		if c == '\r' {
+			if i == len(b)-1 {
+				cw.cr = true
+				continue
+			}
+			if b[i+1] == '\n' {
+				continue
+			}
+		}
+		out = append(out, c)
+	}
+
+	if _, err := cw.w.Write(out); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// Flush writes out a trailing \r, if any
+func (cw *crlfWriter) Flush() error {
+	if !cw.cr {
+		return nil
+	}
+	cw.cr = false
+	_, err := cw.w.Write([]byte{'\r'})
+	return err
+}
@@ -0,0 +1,117 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nxtcoder17/go.pkgs/log"
+)
+
+func Test_Executor_PTY(t *testing.T) {
+	b := new(bytes.Buffer)
+	w := Writer{b: b, m: sync.Mutex{}}
+
+	ex := NewCmdExecutor(context.TODO(), CmdExecutorArgs{
+		Logger: log.New(log.Options{ShowDebugLogs: os.Getenv("DEBUG") == "true"}),
+		Commands: []CommandGroup{
+			{
+				Commands: []func(c context.Context) *exec.Cmd{
+					func(c context.Context) *exec.Cmd {
+						cmd := exec.CommandContext(c, "sh", "-c", `test -t 0 && test -t 1 && test -t 2 && echo tty; stty size; echo err >&2; sleep 5`)
+						cmd.Stdout = &w
+						return cmd
+					},
+				},
+				Names: []string{"app"},
+			},
+		},
+		Output: NewOutputMux(OutputMuxArgs{NoColor: true}),
+		PTY:    true,
+	})
+
+	go ex.Start()
+	defer ex.Stop()
+
+	output := func() string {
+		<-time.After(300 * time.Millisecond)
+		w.m.Lock()
+		defer w.m.Unlock()
+		return b.String()
+	}
+
+	// INFO: fwatcher is not attached to a terminal in tests, so pty gets the default size
+	want := "app | tty\napp | 24 80\napp | err\n"
+	if got := output(); got != want {
+		t.Fatalf("FAILED (initial run)\n\t got: %q\n\twant: %q\n", got, want)
+	}
+
+	ex.OnWatchEvent(Event{Source: "main.go"})
+
+	// INFO: restarted command gets a new pty
+	if got := output(); got != want+want {
+		t.Fatalf("FAILED (restart)\n\t got: %q\n\twant: %q\n", got, want+want)
+	}
+}
+
+func Test_Executor_PTY_Exited(t *testing.T) {
+	b := new(bytes.Buffer)
+	w := Writer{b: b, m: sync.Mutex{}}
+
+	ex := NewCmdExecutor(context.TODO(), CmdExecutorArgs{
+		Logger: log.New(log.Options{ShowDebugLogs: os.Getenv("DEBUG") == "true"}),
+		Commands: []CommandGroup{
+			{
+				Commands: []func(c context.Context) *exec.Cmd{
+					func(c context.Context) *exec.Cmd {
+						cmd := exec.CommandContext(c, "sh", "-c", `seq 1 1000 | tail -n 1`)
+						cmd.Stdout = &w
+						return cmd
+					},
+				},
+			},
+		},
+		PTY: true,
+	})
+
+	if err := ex.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	// INFO: output of an exited command, must be fully copied, once Start returns
+	if got, want := b.String(), "1000\n"; got != want {
+		t.Errorf("FAILED\n\t got: %q\n\twant: %q\n", got, want)
+	}
+}
+
+func Test_CRLFWriter(t *testing.T) {
+	tests := []struct {
+		name   string
+		writes []string
+		want   string
+	}{
+		{name: "1. crlf", writes: []string{"a\r\nb\r\n"}, want: "a\nb\n"},
+		{name: "2. crlf split across writes", writes: []string{"a\r", "\nb\r", "\n"}, want: "a\nb\n"},
+		{name: "3. lone cr", writes: []string{"10%\r20%\r", "30%\r\n"}, want: "10%\r20%\r30%\n"},
+		{name: "4. trailing cr", writes: []string{"a\r"}, want: "a\r"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := new(bytes.Buffer)
+			cw := &crlfWriter{w: b}
+			for _, s := range tt.writes {
+				cw.Write([]byte(s))
+			}
+			cw.Flush()
+
+			if got := b.String(); got != tt.want {
+				t.Errorf("FAILED (%s)\n\t got: %q\n\twant: %q\n", tt.name, got, tt.want)
+			}
+		})
+	}
+}
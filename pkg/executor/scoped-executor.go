@@ -0,0 +1,58 @@
+package executor
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// ScopedExecutor forwards only those watch events to executor, that happened inside one of its dirs
+type ScopedExecutor struct {
+	Executor
+	dirs []string
+}
+
+// NewScopedExecutor scopes executor to dirs, with no dirs, every event is forwarded
+func NewScopedExecutor(executor Executor, dirs ...string) *ScopedExecutor {
+	abs := make([]string, 0, len(dirs))
+	for _, dir := range dirs {
+		abs = append(abs, absPath(dir))
+	}
+	return &ScopedExecutor{Executor: executor, dirs: abs}
+}
+
+// InScope tells whether a change to path, is in scope of this executor
+func (s *ScopedExecutor) InScope(path string) bool {
+	return inDirs(s.dirs, path)
+}
+
+// inDirs tells whether path is inside one of dirs (absolute paths), with no dirs, every path is
+func inDirs(dirs []string, path string) bool {
+	if len(dirs) == 0 {
+		return true
+	}
+
+	path = absPath(path)
+	for _, dir := range dirs {
+		if path == dir || strings.HasPrefix(path, dir+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// OnWatchEvent implements Executor.
+func (s *ScopedExecutor) OnWatchEvent(ev Event) error {
+	if !s.InScope(ev.Source) {
+		return nil
+	}
+	return s.Executor.OnWatchEvent(ev)
+}
+
+func absPath(p string) string {
+	if abs, err := filepath.Abs(p); err == nil {
+		return abs
+	}
+	return filepath.Clean(p)
+}
+
+var _ Executor = (*ScopedExecutor)(nil)
@@ -0,0 +1,52 @@
+package executor
+
+import (
+	"sync"
+	"testing"
+)
+
+type recordingExecutor struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *recordingExecutor) OnWatchEvent(ev Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, ev.Source)
+	return nil
+}
+
+func (r *recordingExecutor) Start() error { return nil }
+func (r *recordingExecutor) Stop() error  { return nil }
+
+func Test_ScopedExecutor(t *testing.T) {
+	tests := []struct {
+		name   string
+		dirs   []string
+		source string
+		want   bool
+	}{
+		{name: "1. no dirs, everything is in scope", dirs: nil, source: "./main.go", want: true},
+		{name: "2. inside dir", dirs: []string{"./cmd/api"}, source: "./cmd/api/main.go", want: true},
+		{name: "3. inside nested dir", dirs: []string{"cmd/api"}, source: "./cmd/api/handlers/user.go", want: true},
+		{name: "4. outside dir", dirs: []string{"./cmd/api"}, source: "./cmd/worker/main.go", want: false},
+		{name: "5. sibling with same prefix", dirs: []string{"./cmd/api"}, source: "./cmd/api-v2/main.go", want: false},
+		{name: "6. one of many dirs", dirs: []string{"./cmd/api", "./pkg"}, source: "./pkg/db/db.go", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := &recordingExecutor{}
+			ex := NewParallelExecutor(NewScopedExecutor(rec, tt.dirs...))
+
+			if err := ex.OnWatchEvent(Event{Source: tt.source}); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := len(rec.events) == 1; got != tt.want {
+				t.Errorf("FAILED (%s)\n\t got: %v\n\twant: %v\n", tt.name, got, tt.want)
+			}
+		})
+	}
+}
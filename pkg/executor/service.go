@@ -0,0 +1,141 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os/exec"
+	"time"
+)
+
+// CommandKind tells how a command in a sequential command group is waited upon, before the next one starts
+type CommandKind string
+
+const (
+	// KindTask is a command, that must exit successfully, before the next command starts
+	KindTask CommandKind = "task"
+	// KindService is a long running command, next command starts once it is ready, while it keeps running
+	KindService CommandKind = "service"
+)
+
+// ReadyCheck tells whether a service is ready, it is polled until it returns true
+type ReadyCheck func(ctx context.Context) bool
+
+// TCPReady is ready, once addr accepts tcp connections
+func TCPReady(addr string) ReadyCheck {
+	return func(ctx context.Context) bool {
+		d := net.Dialer{Timeout: 200 * time.Millisecond}
+		conn, err := d.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return false
+		}
+		conn.Close()
+		return true
+	}
+}
+
+// readyPollInterval is the interval, at which ReadyCheck of a service is polled
+var readyPollInterval = 100 * time.Millisecond
+
+// DefaultReadyTimeout is how long a service gets to be ready, by default
+const DefaultReadyTimeout = 30 * time.Second
+
+var (
+	// ErrServiceExited is returned, when a service exits before it got ready
+	ErrServiceExited = errors.New("service exited before it got ready")
+	// ErrServiceNotReady is returned, when a service does not get ready within its ready timeout. Service gets stopped
+	ErrServiceNotReady = errors.New("service did not get ready in time")
+)
+
+// kind is the kind of i-th command, it defaults to KindTask
+func (cg CommandGroup) kind(i int) CommandKind {
+	if i < len(cg.Kinds) && cg.Kinds[i] != "" {
+		return cg.Kinds[i]
+	}
+	return KindTask
+}
+
+// readyCheck is the ReadyCheck of i-th command, if any
+func (cg CommandGroup) readyCheck(i int) ReadyCheck {
+	if i < len(cg.ReadyChecks) {
+		return cg.ReadyChecks[i]
+	}
+	return nil
+}
+
+// readyTimeout is the ready timeout of i-th command, it defaults to DefaultReadyTimeout
+func (cg CommandGroup) readyTimeout(i int) time.Duration {
+	if i < len(cg.ReadyTimeouts) && cg.ReadyTimeouts[i] > 0 {
+		return cg.ReadyTimeouts[i]
+	}
+	return DefaultReadyTimeout
+}
+
+// startService starts a service, and waits (up to timeout) for it to get ready. Service keeps running, until it exits,
+// or ctx is cancelled, and its result is sent on the returned channel.
+func (ex *CmdExecutor) startService(ctx context.Context, newCmd func(context.Context) *exec.Cmd, check ReadyCheck, timeout time.Duration, args execArgs) (<-chan error, error) {
+	started := make(chan struct{})
+	args.OnStart = func(cmd *exec.Cmd) {
+		close(started)
+	}
+
+	// INFO: service has a context of its own, so that it can be stopped, if it does not get ready in time
+	serviceCtx, stop := context.WithCancel(ctx)
+
+	exited := make(chan error, 1)
+	go func() {
+		exited <- ex.exec(serviceCtx, newCmd, args)
+		stop()
+	}()
+
+	select {
+	case <-started:
+	case err := <-exited:
+		return nil, errors.Join(ErrServiceExited, err)
+	}
+
+	if check == nil {
+		args.Logger.Debug("service started")
+		return exited, nil
+	}
+
+	readyCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(readyPollInterval)
+	defer ticker.Stop()
+
+	for !check(readyCtx) {
+		select {
+		case <-ticker.C:
+		case err := <-exited:
+			return nil, errors.Join(ErrServiceExited, err)
+		case <-readyCtx.Done():
+			if ctx.Err() != nil {
+				// INFO: exec kills the service, and reports back, once it has exited
+				<-exited
+				return nil, ctx.Err()
+			}
+
+			args.Logger.Error("service did not get ready in time, stopping it", "timeout", timeout)
+			stop()
+			<-exited
+			return nil, fmt.Errorf("%w (within %s)", ErrServiceNotReady, timeout)
+		}
+	}
+
+	args.Logger.Debug("service is ready")
+	return exited, nil
+}
+
+// waitServices waits for services, and returns the first error among them
+func waitServices(services []<-chan error) error {
+	var firstErr error
+	for _, exited := range services {
+		if err := <-exited; err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("service failed: %w", err)
+		}
+	}
+	return firstErr
+}
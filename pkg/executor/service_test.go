@@ -0,0 +1,178 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nxtcoder17/go.pkgs/log"
+)
+
+func Test_Executor_Services(t *testing.T) {
+	fileExists := func(path string) ReadyCheck {
+		return func(ctx context.Context) bool {
+			_, err := os.Stat(path)
+			return err == nil
+		}
+	}
+
+	// INFO: services signal readiness through their output, as their output up to it, lands before output of the next commands
+	printed := func(w *Writer, word string) ReadyCheck {
+		return func(ctx context.Context) bool {
+			w.m.Lock()
+			defer w.m.Unlock()
+			return count(w.b.String(), word) > 0
+		}
+	}
+
+	tests := []struct {
+		name     string
+		commands func(w *Writer, dir string) CommandGroup
+		output   []string
+		err      error
+		// within is the time, within which Start must return
+		within time.Duration
+	}{
+		{
+			name: "1. build, run server, and run smoke tests against it",
+			commands: func(w *Writer, dir string) CommandGroup {
+				doneFile := filepath.Join(dir, "done")
+				return CommandGroup{
+					Commands: []func(c context.Context) *exec.Cmd{
+						shCmd(w, "echo build"),
+						shCmd(w, fmt.Sprintf("echo server; sleep 0.3; echo ready; while [ ! -f %s ]; do sleep 0.05; done; echo server:stopping", doneFile)),
+						shCmd(w, "echo smoke"),
+						shCmd(w, fmt.Sprintf("touch %s", doneFile)),
+					},
+					Kinds:       []CommandKind{KindTask, KindService, KindTask, KindTask},
+					ReadyChecks: []ReadyCheck{nil, printed(w, "ready")},
+				}
+			},
+			output: []string{"build", "server", "ready", "smoke", "server:stopping"},
+			within: 2 * time.Second,
+		},
+		{
+			name: "2. service exits before it gets ready",
+			commands: func(w *Writer, dir string) CommandGroup {
+				return CommandGroup{
+					Commands: []func(c context.Context) *exec.Cmd{
+						shCmd(w, "echo server; exit 1"),
+						shCmd(w, "echo smoke"),
+					},
+					Kinds:       []CommandKind{KindService},
+					ReadyChecks: []ReadyCheck{fileExists(filepath.Join(dir, "ready"))},
+				}
+			},
+			output: []string{"server"},
+			err:    ErrServiceExited,
+			within: 2 * time.Second,
+		},
+		{
+			name: "3. failing task stops the running services",
+			commands: func(w *Writer, dir string) CommandGroup {
+				return CommandGroup{
+					Commands: []func(c context.Context) *exec.Cmd{
+						shCmd(w, "echo server; sleep 0.3; echo ready; sleep 5; echo server:stopping"),
+						shCmd(w, "echo smoke; exit 1"),
+					},
+					Kinds:       []CommandKind{KindService, KindTask},
+					ReadyChecks: []ReadyCheck{printed(w, "ready")},
+				}
+			},
+			output: []string{"server", "ready", "smoke"},
+			err:    &exec.ExitError{},
+			within: 2 * time.Second,
+		},
+		{
+			name: "4. service that does not get ready in time, is stopped",
+			commands: func(w *Writer, dir string) CommandGroup {
+				return CommandGroup{
+					Commands: []func(c context.Context) *exec.Cmd{
+						shCmd(w, "echo server; sleep 5; echo server:stopping"),
+						shCmd(w, "echo smoke"),
+					},
+					Kinds:         []CommandKind{KindService},
+					ReadyChecks:   []ReadyCheck{fileExists(filepath.Join(dir, "ready"))},
+					ReadyTimeouts: []time.Duration{300 * time.Millisecond},
+				}
+			},
+			output: []string{"server"},
+			err:    ErrServiceNotReady,
+			within: 2 * time.Second,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := new(bytes.Buffer)
+			w := Writer{b: b, m: sync.Mutex{}}
+
+			ex := NewCmdExecutor(context.TODO(), CmdExecutorArgs{
+				Logger:   log.New(log.Options{ShowDebugLogs: os.Getenv("DEBUG") == "true"}),
+				Commands: []CommandGroup{tt.commands(&w, t.TempDir())},
+			})
+			defer ex.Stop()
+
+			errCh := make(chan error, 1)
+			go func() {
+				errCh <- ex.Start()
+			}()
+
+			var err error
+			select {
+			case err = <-errCh:
+			case <-time.After(tt.within):
+				t.Fatalf("FAILED (%s), Start did not return within %s", tt.name, tt.within)
+			}
+
+			switch want := tt.err.(type) {
+			case nil:
+				if err != nil {
+					t.Errorf("FAILED (%s)\n\t got: %v\n\twant: <nil>\n", tt.name, err)
+				}
+			case *exec.ExitError:
+				if !errors.As(err, &want) {
+					t.Errorf("FAILED (%s)\n\t got: %v\n\twant: exit error\n", tt.name, err)
+				}
+			default:
+				if !errors.Is(err, want) {
+					t.Errorf("FAILED (%s)\n\t got: %v\n\twant: %v\n", tt.name, err, want)
+				}
+			}
+
+			w.m.Lock()
+			got := strings.Join(strings.Fields(b.String()), ",")
+			w.m.Unlock()
+
+			if want := strings.Join(tt.output, ","); got != want {
+				t.Errorf("FAILED (%s)\n\t got: %s\n\twant: %s\n", tt.name, got, want)
+			}
+		})
+	}
+}
+
+func Test_TCPReady(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addr := l.Addr().String()
+	if !TCPReady(addr)(context.TODO()) {
+		t.Errorf("FAILED, expected (%s) to be ready, while listening", addr)
+	}
+
+	l.Close()
+	if TCPReady(addr)(context.TODO()) {
+		t.Errorf("FAILED, expected (%s) not to be ready, once closed", addr)
+	}
+}
@@ -0,0 +1,39 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync/atomic"
+)
+
+// trackEnv is the env var, that marks every process of a command (descendants inherit it), so that they can be found,
+// even after they have escaped its process group, and got reparented
+const trackEnv = "FWATCHER_TRACK"
+
+var trackSeq atomic.Int64
+
+// newTrackMarker is a unique marker, for processes of a single command
+func newTrackMarker() string {
+	return fmt.Sprintf("%d-%d", os.Getpid(), trackSeq.Add(1))
+}
+
+// releaseTracked kills processes marked with marker, if command got cancelled. Otherwise, processes it left behind
+// keep running, until its execution gets cancelled, i.e. on restart
+func (ex *CmdExecutor) releaseTracked(ctx context.Context, marker string, logger *slog.Logger) {
+	kill := func() {
+		if pids := ex.reaper.kill(marker); len(pids) > 0 {
+			logger.Debug("killed processes left behind by command", "pids", pids)
+		}
+	}
+
+	e, ok := ctx.Value(executionKey{}).(*execution)
+	if ctx.Err() != nil || !ok || len(ex.reaper.tracked(marker)) == 0 {
+		kill()
+		return
+	}
+
+	logger.Debug("command left processes behind, they will be killed on restart")
+	e.leftBehind(kill)
+}
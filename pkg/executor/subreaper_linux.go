@@ -0,0 +1,223 @@
+package executor
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// reaper makes fwatcher a child subreaper, so that processes daemonized by commands (i.e. double forked) get reparented
+// to fwatcher, instead of init. It reaps such orphans, but never the child processes, that someone else waits upon.
+type reaper struct {
+	mu sync.Mutex
+	// managed are processes of commands, that are waited upon by their executor
+	managed map[int]struct{}
+	// adopted are orphaned processes of commands, that got reparented to fwatcher
+	adopted map[int]struct{}
+	// groups are process groups of commands (as commands start in process groups of their own), that still have processes
+	// in them. Orphans in them are adopted even as zombies, as env (and so, marker) of a zombie can not be read
+	groups map[int]struct{}
+}
+
+// subreaper is the process wide reaper, as subreaper is a process wide attribute
+var subreaper = sync.OnceValues(func() (*reaper, error) {
+	if err := unix.Prctl(unix.PR_SET_CHILD_SUBREAPER, 1, 0, 0, 0); err != nil {
+		return nil, err
+	}
+
+	r := &reaper{managed: map[int]struct{}{}, adopted: map[int]struct{}{}, groups: map[int]struct{}{}}
+	go r.run()
+	return r, nil
+})
+
+func newReaper() (*reaper, error) {
+	return subreaper()
+}
+
+func (r *reaper) run() {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGCHLD)
+
+	// INFO: orphans get reparented silently, so they are also looked for periodically, while they are alive, and marked
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-sigs:
+		case <-ticker.C:
+		}
+		r.reap()
+	}
+}
+
+// start starts cmd with start, and marks its process as managed, before anything could reap it
+func (r *reaper) start(cmd *exec.Cmd, start func(cmd *exec.Cmd) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := start(cmd); err != nil {
+		return err
+	}
+	r.managed[cmd.Process.Pid] = struct{}{}
+	r.groups[cmd.Process.Pid] = struct{}{}
+	return nil
+}
+
+// exited unmarks pid as managed, once it has been waited upon
+func (r *reaper) exited(pid int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.managed, pid)
+}
+
+// reap adopts children of fwatcher, that it did not start itself, but are in process groups of commands (zombie, or not),
+// or are marked, and reaps the ones that have exited
+func (r *reaper) reap() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	self := os.Getpid()
+	groups := map[int]struct{}{}
+	for _, p := range processes() {
+		groups[p.pgrp] = struct{}{}
+
+		if p.ppid != self {
+			continue
+		}
+		if _, ok := r.managed[p.pid]; ok {
+			continue
+		}
+		if _, ok := r.groups[p.pgrp]; ok || (!p.zombie && trackMarker(p.pid) != "") {
+			r.adopted[p.pid] = struct{}{}
+		}
+	}
+
+	for pgid := range r.groups {
+		if _, ok := groups[pgid]; !ok {
+			delete(r.groups, pgid)
+		}
+	}
+
+	for pid := range r.adopted {
+		r.wait(pid)
+	}
+}
+
+// wait reaps pid, if it has exited, and tells whether it is gone, it must be called with r.mu held
+func (r *reaper) wait(pid int) bool {
+	var ws unix.WaitStatus
+	wpid, err := unix.Wait4(pid, &ws, unix.WNOHANG, nil)
+	if wpid == pid || err == unix.ECHILD {
+		delete(r.adopted, pid)
+		return true
+	}
+	return false
+}
+
+// tracked are the pids of live processes, marked with marker
+func (r *reaper) tracked(marker string) []int {
+	var pids []int
+	for _, p := range processes() {
+		if !p.zombie && trackMarker(p.pid) == marker {
+			pids = append(pids, p.pid)
+		}
+	}
+	return pids
+}
+
+// kill kills live processes marked with marker, and reaps the ones, that are (or, get reparented as) children of fwatcher
+func (r *reaper) kill(marker string) []int {
+	pids := r.tracked(marker)
+	if len(pids) == 0 {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	pending := make(map[int]struct{}, len(pids))
+	for _, pid := range pids {
+		if _, ok := r.managed[pid]; ok {
+			// INFO: its executor waits upon it
+			continue
+		}
+		syscall.Kill(pid, syscall.SIGKILL)
+		r.adopted[pid] = struct{}{}
+		pending[pid] = struct{}{}
+	}
+
+	// INFO: a killed process gets reparented to fwatcher, once its parent (that is also being killed) is gone
+	for i := 0; i < 100 && len(pending) > 0; i++ {
+		for pid := range pending {
+			if _, err := os.Stat("/proc/" + strconv.Itoa(pid)); err != nil || r.wait(pid) {
+				delete(pending, pid)
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	return pids
+}
+
+type process struct {
+	pid    int
+	ppid   int
+	pgrp   int
+	zombie bool
+}
+
+// processes lists all the processes, from /proc
+func processes() []process {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil
+	}
+
+	var procs []process
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+
+		b, err := os.ReadFile("/proc/" + entry.Name() + "/stat")
+		if err != nil {
+			continue
+		}
+
+		// INFO: stat looks like, <pid> (<comm>) <state> <ppid> <pgrp> ..., where comm may contain spaces, and parentheses
+		fields := strings.Fields(string(b[bytes.LastIndexByte(b, ')')+1:]))
+		if len(fields) < 3 {
+			continue
+		}
+
+		ppid, _ := strconv.Atoi(fields[1])
+		pgrp, _ := strconv.Atoi(fields[2])
+		procs = append(procs, process{pid: pid, ppid: ppid, pgrp: pgrp, zombie: fields[0] == "Z"})
+	}
+	return procs
+}
+
+// trackMarker is the value of trackEnv, that process pid got started with, if any
+func trackMarker(pid int) string {
+	b, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/environ")
+	if err != nil {
+		return ""
+	}
+
+	for _, kv := range bytes.Split(b, []byte{0}) {
+		if v, ok := bytes.CutPrefix(kv, []byte(trackEnv+"=")); ok {
+			return string(v)
+		}
+	}
+	return ""
+}
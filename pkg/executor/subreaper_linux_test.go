@@ -0,0 +1,122 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nxtcoder17/go.pkgs/log"
+)
+
+func Test_Executor_Subreaper(t *testing.T) {
+	tests := []struct {
+		name string
+		// script starts a process, that escapes its process group, and writes its pid to $PID_FILE
+		script string
+	}{
+		{
+			name:   "1. daemonized process of a running command",
+			script: `(setsid sh -c 'echo $$ > $PID_FILE; exec sleep 100' &); sleep 100`,
+		},
+		{
+			name:   "2. daemonized process of an exited command",
+			script: `(setsid sh -c 'echo $$ > $PID_FILE; exec sleep 100' &)`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pidFile := filepath.Join(t.TempDir(), "pid")
+
+			ex := NewCmdExecutor(context.TODO(), CmdExecutorArgs{
+				Logger: log.New(log.Options{ShowDebugLogs: os.Getenv("DEBUG") == "true"}),
+				Commands: []CommandGroup{
+					{
+						Commands: []func(c context.Context) *exec.Cmd{
+							func(c context.Context) *exec.Cmd {
+								cmd := exec.CommandContext(c, "sh", "-c", tt.script)
+								cmd.Env = append(os.Environ(), "PID_FILE="+pidFile)
+								return cmd
+							},
+						},
+					},
+				},
+				Subreaper: true,
+			})
+
+			go ex.Start()
+			defer ex.Stop()
+
+			pid := waitForPID(t, pidFile)
+			if !alive(pid) {
+				t.Fatalf("FAILED (%s), daemonized process (%d) is not running", tt.name, pid)
+			}
+
+			if ppid := parentPID(pid); ppid != os.Getpid() {
+				t.Fatalf("FAILED (%s), daemonized process did not get reparented to subreaper\n\t got: %d\n\twant: %d\n", tt.name, ppid, os.Getpid())
+			}
+
+			os.Remove(pidFile)
+			ex.OnWatchEvent(Event{Source: "main.go"})
+			waitForPID(t, pidFile)
+
+			// INFO: it must be killed, and reaped, i.e. not be left as a zombie either
+			if _, err := os.Stat(fmt.Sprintf("/proc/%d", pid)); err == nil {
+				t.Errorf("FAILED (%s), daemonized process (%d) still exists after restart (alive: %v)", tt.name, pid, alive(pid))
+			}
+		})
+	}
+}
+
+func Test_Executor_Subreaper_ShortLivedOrphans(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "pid")
+
+	ex := NewCmdExecutor(context.TODO(), CmdExecutorArgs{
+		Logger: log.New(log.Options{ShowDebugLogs: os.Getenv("DEBUG") == "true"}),
+		Commands: []CommandGroup{
+			{
+				Commands: []func(c context.Context) *exec.Cmd{
+					func(c context.Context) *exec.Cmd {
+						// INFO: orphan exits well before the periodic scan of the reaper
+						cmd := exec.CommandContext(c, "sh", "-c", `echo $$ > $PID_FILE; (sleep 0.2 &); sleep 5`)
+						cmd.Env = append(os.Environ(), "PID_FILE="+pidFile)
+						return cmd
+					},
+				},
+			},
+		},
+		Subreaper: true,
+	})
+
+	go ex.Start()
+	defer ex.Stop()
+
+	// INFO: command is the leader of its process group
+	pgid := waitForPID(t, pidFile)
+	<-time.After(1500 * time.Millisecond)
+
+	var zombies []int
+	for _, p := range processes() {
+		if p.ppid == os.Getpid() && p.pgrp == pgid && p.zombie {
+			zombies = append(zombies, p.pid)
+		}
+	}
+
+	if len(zombies) > 0 {
+		t.Errorf("FAILED, orphans were not reaped\n\t got: zombies %v\n\twant: none\n", zombies)
+	}
+}
+
+// parentPID is the parent pid of process pid
+func parentPID(pid int) int {
+	for _, p := range processes() {
+		if p.pid == pid {
+			return p.ppid
+		}
+	}
+	return 0
+}
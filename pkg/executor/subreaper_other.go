@@ -0,0 +1,23 @@
+//go:build !linux
+
+package executor
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// reaper makes fwatcher a child subreaper, subreapers exist only on linux
+type reaper struct{}
+
+func newReaper() (*reaper, error) {
+	return nil, fmt.Errorf("subreaper is only supported on linux")
+}
+
+func (r *reaper) start(cmd *exec.Cmd, start func(cmd *exec.Cmd) error) error { return start(cmd) }
+
+func (r *reaper) exited(pid int) {}
+
+func (r *reaper) tracked(marker string) []int { return nil }
+
+func (r *reaper) kill(marker string) []int { return nil }
@@ -0,0 +1,103 @@
+package executor
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"sync"
+
+	"golang.org/x/sys/unix"
+)
+
+// terminal is the controlling terminal of fwatcher (i.e. its stdin), that interactive commands are handed, as their
+// process group becomes its foreground process group. fwatcher takes it back, once they exit, or are killed on restart
+type terminal struct {
+	f *os.File
+	// pgrp is fwatcher's own process group
+	pgrp int
+	// modes are terminal modes, as fwatcher found them. Commands might leave the terminal in raw mode (like, when they
+	// get killed on restart), so they are restored, every time fwatcher takes the terminal back
+	modes *unix.Termios
+
+	mu sync.Mutex
+	// holders are process groups of running commands, that have been handed the terminal, in the order they started
+	holders []int
+}
+
+// openTerminal opens f as a terminal, that commands can be handed. It fails if f is not a terminal, or if fwatcher is
+// not in its foreground (like, when it has been run in background by a shell)
+func openTerminal(f *os.File) (*terminal, error) {
+	fd := int(f.Fd())
+
+	modes, err := unix.IoctlGetTermios(fd, ioctlGetTermios)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a terminal: %w", f.Name(), err)
+	}
+
+	fg, err := unix.IoctlGetInt(fd, unix.TIOCGPGRP)
+	if err != nil {
+		return nil, err
+	}
+
+	pgrp := unix.Getpgrp()
+	if fg != pgrp {
+		return nil, fmt.Errorf("fwatcher (process group %d) is not in the foreground of its terminal (process group %d)", pgrp, fg)
+	}
+
+	return &terminal{f: f, pgrp: pgrp, modes: modes}, nil
+}
+
+// start wraps start of a command, so that the command starts in a process group of its own, in the foreground of
+// the terminal
+func (t *terminal) start(start func(*exec.Cmd) error) func(*exec.Cmd) error {
+	return func(cmd *exec.Cmd) error {
+		// INFO: child process hands itself the terminal (i.e. tcsetpgrp), before exec
+		cmd.SysProcAttr.Setpgid = true
+		cmd.SysProcAttr.Foreground = true
+		cmd.SysProcAttr.Ctty = int(t.f.Fd())
+
+		t.mu.Lock()
+		defer t.mu.Unlock()
+
+		if err := start(cmd); err != nil {
+			return err
+		}
+		t.holders = append(t.holders, cmd.Process.Pid)
+		return nil
+	}
+}
+
+// release takes the terminal back from process group pgid, once its processes have exited (or, have been killed).
+// If other commands are still holding the terminal, it is handed to the last started of them, instead
+func (t *terminal) release(pgid int) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.holders = slices.DeleteFunc(t.holders, func(p int) bool { return p == pgid })
+
+	fd := int(t.f.Fd())
+	fg, err := unix.IoctlGetInt(fd, unix.TIOCGPGRP)
+	if err != nil {
+		return err
+	}
+
+	if slices.Contains(t.holders, fg) {
+		return nil
+	}
+
+	if len(t.holders) > 0 {
+		holder := t.holders[len(t.holders)-1]
+		return withSIGTTOUBlocked(func() error {
+			return unix.IoctlSetPointerInt(fd, unix.TIOCSPGRP, holder)
+		})
+	}
+
+	// INFO: fwatcher is in background now, and changing its terminal from background raises SIGTTOU, that stops it
+	return withSIGTTOUBlocked(func() error {
+		if err := unix.IoctlSetPointerInt(fd, unix.TIOCSPGRP, t.pgrp); err != nil {
+			return err
+		}
+		return unix.IoctlSetTermios(fd, ioctlSetTermios, t.modes)
+	})
+}
@@ -0,0 +1,36 @@
+package executor
+
+import (
+	"runtime"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	ioctlGetTermios = unix.TCGETS
+	ioctlSetTermios = unix.TCSETS
+)
+
+// withSIGTTOUBlocked runs fn, with SIGTTOU blocked on its thread, so that fn can change the terminal from background.
+// Unlike ignoring it, blocking does not leak into commands started meanwhile
+func withSIGTTOUBlocked(fn func() error) error {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	var set, old unix.Sigset_t
+	sigaddset(&set, unix.SIGTTOU)
+	if err := unix.PthreadSigmask(unix.SIG_BLOCK, &set, &old); err != nil {
+		return err
+	}
+	defer unix.PthreadSigmask(unix.SIG_SETMASK, &old, nil)
+
+	return fn()
+}
+
+func sigaddset(set *unix.Sigset_t, sig unix.Signal) {
+	// INFO: words of sigset are 32, or 64 bits wide, depending on the arch
+	bits := uint(unsafe.Sizeof(set.Val[0])) * 8
+	n := uint(sig) - 1
+	set.Val[n/bits] |= 1 << (n % bits)
+}
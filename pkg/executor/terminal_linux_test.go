@@ -0,0 +1,145 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/creack/pty"
+	"github.com/nxtcoder17/go.pkgs/log"
+	"golang.org/x/sys/unix"
+)
+
+// terminalScriptEnv is the script, that Test_TerminalHelperProcess runs in interactive mode
+const terminalScriptEnv = "FWATCHER_TEST_TERMINAL_SCRIPT"
+
+// Test_TerminalHelperProcess is fwatcher, run by Test_Executor_Interactive, attached to a pty (as its controlling
+// terminal). It restarts the command on SIGUSR1, and on SIGUSR2, stops it and prints state of its terminal
+func Test_TerminalHelperProcess(t *testing.T) {
+	script := os.Getenv(terminalScriptEnv)
+	if script == "" {
+		t.Skip("run by Test_Executor_Interactive")
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGUSR1, syscall.SIGUSR2)
+
+	ex := NewCmdExecutor(context.TODO(), CmdExecutorArgs{
+		Logger: log.New(log.Options{ShowDebugLogs: os.Getenv("DEBUG") == "true"}),
+		Commands: []CommandGroup{
+			{
+				Commands: []func(c context.Context) *exec.Cmd{
+					func(c context.Context) *exec.Cmd {
+						cmd := exec.CommandContext(c, "sh", "-c", script)
+						cmd.Stdout = os.Stdout
+						return cmd
+					},
+				},
+			},
+		},
+		Interactive: true,
+	})
+
+	if ex.terminal == nil {
+		t.Fatalf("FAILED, stdin is not a terminal")
+	}
+
+	go ex.Start()
+
+	for sig := range sigs {
+		if sig == syscall.SIGUSR1 {
+			ex.OnWatchEvent(Event{Source: "main.go"})
+			continue
+		}
+		ex.Stop()
+		break
+	}
+
+	fd := int(os.Stdin.Fd())
+	fg, err := unix.IoctlGetInt(fd, unix.TIOCGPGRP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	modes, err := unix.IoctlGetTermios(fd, unix.TCGETS)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fmt.Printf("terminal: foreground=%t cooked=%t\n", fg == unix.Getpgrp(), modes.Lflag&(unix.ICANON|unix.ECHO) == unix.ICANON|unix.ECHO)
+}
+
+func Test_Executor_Interactive(t *testing.T) {
+	// INFO: command tells, whether it is in foreground of its terminal, i.e. its process group (5th field of stat) is
+	// the foreground process group of its terminal (8th field)
+	const foreground = `set -- $(cat /proc/$$/stat); [ "$5" = "$8" ] && echo foreground`
+
+	tests := []struct {
+		name   string
+		script string
+	}{
+		{
+			name:   "1. command in raw mode, killed on restart",
+			script: `stty raw -echo; ` + foreground + `; exec sleep 100`,
+		},
+		{
+			name:   "2. command in raw mode, that exits on its own",
+			script: `stty raw -echo; ` + foreground,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := exec.Command(os.Args[0], "-test.run=^Test_TerminalHelperProcess$")
+			cmd.Env = append(os.Environ(), terminalScriptEnv+"="+tt.script)
+
+			ptmx, err := pty.Start(cmd)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer ptmx.Close()
+			defer cmd.Process.Kill()
+
+			b := new(bytes.Buffer)
+			w := Writer{b: b, m: sync.Mutex{}}
+			go io.Copy(&w, ptmx)
+
+			waitFor := func(stage string, want string, count int) {
+				t.Helper()
+				for range 50 {
+					<-time.After(100 * time.Millisecond)
+					w.m.Lock()
+					got := b.String()
+					w.m.Unlock()
+					if strings.Count(got, want) >= count {
+						return
+					}
+				}
+				w.m.Lock()
+				defer w.m.Unlock()
+				t.Fatalf("FAILED (%s: %s)\n\t got: %q\n\twant: %d x %q\n", tt.name, stage, b.String(), count, want)
+			}
+
+			waitFor("initial run", "foreground", 1)
+
+			// INFO: restart must not take down fwatcher itself, and restarted command gets the terminal too
+			cmd.Process.Signal(syscall.SIGUSR1)
+			waitFor("restart", "foreground", 2)
+
+			cmd.Process.Signal(syscall.SIGUSR2)
+			waitFor("stop", "terminal: foreground=true cooked=true", 1)
+
+			if err := cmd.Wait(); err != nil {
+				t.Errorf("FAILED (%s)\n\t got: %s\n\twant: %v\n", tt.name, err, nil)
+			}
+		})
+	}
+}
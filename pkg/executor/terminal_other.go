@@ -0,0 +1,24 @@
+//go:build !linux
+
+package executor
+
+import (
+	"os/signal"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	ioctlGetTermios = unix.TIOCGETA
+	ioctlSetTermios = unix.TIOCSETA
+)
+
+// withSIGTTOUBlocked runs fn, with SIGTTOU ignored, so that fn can change the terminal from background. Commands are
+// started under terminal's lock, so they do not inherit it
+func withSIGTTOUBlocked(fn func() error) error {
+	signal.Ignore(syscall.SIGTTOU)
+	defer signal.Reset(syscall.SIGTTOU)
+
+	return fn()
+}
@@ -0,0 +1,209 @@
+package godeps
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+/*
+godeps computes files (and directories), that a go package depends upon, with `go list -deps`.
+Standard library, and modules from module cache are left out, as they don't change while developing,
+while packages from the main module, workspace modules, and local replaces are kept.
+*/
+
+// Graph is set of files, and directories containing them, that a go package depends upon
+type Graph struct {
+	Files map[string]struct{}
+	Dirs  map[string]struct{}
+
+	// embeds are //go:embed patterns of packages, by their directory
+	embeds map[string][]string
+}
+
+type module struct {
+	Path    string
+	Dir     string
+	GoMod   string
+	Main    bool
+	Replace *module
+}
+
+type pkg struct {
+	Dir        string
+	ImportPath string
+	Standard   bool
+	Module     *module
+
+	GoFiles    []string
+	CgoFiles   []string
+	CFiles     []string
+	CXXFiles   []string
+	HFiles     []string
+	SFiles     []string
+	SysoFiles  []string
+	EmbedFiles []string
+
+	EmbedPatterns []string
+}
+
+// Load runs `go list -deps` for package (e.g. ./cmd/api) in dir, and builds its dependency graph
+func Load(ctx context.Context, dir string, pkgName string) (*Graph, error) {
+	modCache, err := goEnv(ctx, dir, "GOMODCACHE")
+	if err != nil {
+		return nil, err
+	}
+
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+
+	// INFO: -e reports errors (like a syntax error, while file is being edited) per package, instead of failing altogether
+	cmd := exec.CommandContext(ctx, "go", "list", "-e", "-deps", "-json", pkgName)
+	cmd.Dir = dir
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("go list %s failed: %w (%s)", pkgName, err, strings.TrimSpace(stderr.String()))
+	}
+
+	g := Graph{
+		Files:  map[string]struct{}{},
+		Dirs:   map[string]struct{}{},
+		embeds: map[string][]string{},
+	}
+
+	dec := json.NewDecoder(stdout)
+	for {
+		var p pkg
+		if err := dec.Decode(&p); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+
+		if p.Standard || p.Dir == "" {
+			continue
+		}
+
+		if inDir(p.Dir, modCache) {
+			continue
+		}
+
+		for _, files := range [][]string{p.GoFiles, p.CgoFiles, p.CFiles, p.CXXFiles, p.HFiles, p.SFiles, p.SysoFiles, p.EmbedFiles} {
+			for _, f := range files {
+				g.add(filepath.Join(p.Dir, f))
+			}
+		}
+
+		if len(p.EmbedPatterns) > 0 {
+			g.embeds[p.Dir] = p.EmbedPatterns
+		}
+
+		if m := p.Module; m != nil {
+			if m.Replace != nil {
+				m = m.Replace
+			}
+			if m.GoMod != "" && !inDir(m.GoMod, modCache) {
+				g.add(m.GoMod)
+			}
+		}
+	}
+
+	return &g, nil
+}
+
+func (g *Graph) add(file string) {
+	g.Files[file] = struct{}{}
+	g.Dirs[filepath.Dir(file)] = struct{}{}
+}
+
+// Contains tells whether a file is part of the graph. New go files (not tests) in graph's directories are also part of it,
+// as they would be compiled in, and so are go.mod, go.sum, and go.work files, and new files matching //go:embed patterns
+func (g *Graph) Contains(file string) bool {
+	if _, ok := g.Files[file]; ok {
+		return true
+	}
+
+	if g.Embeds(file) {
+		return true
+	}
+
+	if _, ok := g.Dirs[filepath.Dir(file)]; !ok {
+		return false
+	}
+
+	switch base := filepath.Base(file); {
+	case base == "go.mod", base == "go.sum", base == "go.work":
+		return true
+	case strings.HasSuffix(base, ".go") && !strings.HasSuffix(base, "_test.go"):
+		return true
+	default:
+		return false
+	}
+}
+
+// Embeds tells whether a file is (or, once created, would be) embedded by a package of the graph
+func (g *Graph) Embeds(file string) bool {
+	for dir, patterns := range g.embeds {
+		if !inDir(file, dir) {
+			continue
+		}
+
+		rel, err := filepath.Rel(dir, file)
+		if err != nil {
+			continue
+		}
+
+		for _, pattern := range patterns {
+			if embedMatch(pattern, filepath.ToSlash(rel)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// embedMatch tells whether //go:embed pattern matches file (relative to package directory). A pattern matching a
+// directory, embeds files in it recursively, except the ones whose names begin with . or _, unless pattern has all: prefix
+func embedMatch(pattern string, file string) bool {
+	pattern, all := strings.CutPrefix(pattern, "all:")
+
+	parts := strings.Split(file, "/")
+	for i := 1; i <= len(parts); i++ {
+		if ok, _ := path.Match(pattern, strings.Join(parts[:i], "/")); !ok {
+			continue
+		}
+
+		if !all {
+			for _, part := range parts[i:] {
+				if strings.HasPrefix(part, ".") || strings.HasPrefix(part, "_") {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func inDir(p string, dir string) bool {
+	return dir != "" && strings.HasPrefix(p, dir+string(filepath.Separator))
+}
+
+func goEnv(ctx context.Context, dir string, key string) (string, error) {
+	cmd := exec.CommandContext(ctx, "go", "env", key)
+	cmd.Dir = dir
+	b, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("go env %s failed: %w", key, err)
+	}
+	return strings.TrimSpace(string(b)), nil
+}
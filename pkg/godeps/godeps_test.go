@@ -0,0 +1,96 @@
+package godeps
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_Load(t *testing.T) {
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]string{
+		"app/go.mod": "module example.com/app\n\ngo 1.22\n\nrequire example.com/lib v0.0.0\n\nreplace example.com/lib => ../lib\n",
+		"app/main.go": `package main
+
+import (
+	"embed"
+
+	"example.com/app/internal/used"
+	"example.com/lib"
+)
+
+//go:embed static/index.html
+var index string
+
+//go:embed assets
+var assets embed.FS
+
+func main() { used.Used(); lib.Lib() }
+`,
+		"app/main_test.go":                "package main\n",
+		"app/static/index.html":           "<html></html>",
+		"app/assets/app.css":              "body {}",
+		"app/internal/used/used.go":       "package used\n\nfunc Used() {}\n",
+		"app/internal/unused/unused.go":   "package unused\n",
+		"app/cmd/other/main.go":           "package main\n\nfunc main() {}\n",
+		"lib/go.mod":                      "module example.com/lib\n\ngo 1.22\n",
+		"lib/lib.go":                      "package lib\n\nfunc Lib() {}\n",
+		"lib/internal/notused/notused.go": "package notused\n",
+	}
+
+	for name, content := range files {
+		p := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	g, err := Load(context.TODO(), filepath.Join(root, "app"), ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		file string
+		want bool
+	}{
+		{file: "app/main.go", want: true},
+		{file: "app/go.mod", want: true},
+		{file: "app/static/index.html", want: true},
+		{file: "app/internal/used/used.go", want: true},
+		{file: "lib/lib.go", want: true},
+		{file: "lib/go.mod", want: true},
+
+		// INFO: new go files in graph's directories are part of it
+		{file: "app/new.go", want: true},
+
+		{file: "app/main_test.go", want: false},
+		{file: "app/internal/unused/unused.go", want: false},
+		{file: "app/cmd/other/main.go", want: false},
+		{file: "lib/internal/notused/notused.go", want: false},
+		{file: "app/static/other.html", want: false},
+
+		// INFO: new files in embedded directories are part of it, except hidden ones
+		{file: "app/assets/app.css", want: true},
+		{file: "app/assets/new.css", want: true},
+		{file: "app/assets/js/new.js", want: true},
+		{file: "app/assets/.new.css", want: false},
+		{file: "app/assets/_js/new.js", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			if got := g.Contains(filepath.Join(root, tt.file)); got != tt.want {
+				t.Errorf("FAILED (%s)\n\t got: %v\n\twant: %v\n", tt.file, got, tt.want)
+			}
+		})
+	}
+}
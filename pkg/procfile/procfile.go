@@ -0,0 +1,65 @@
+// Package procfile parses Procfiles, i.e. files with lines like `web: go run ./cmd/api`, as used by foreman, and heroku
+package procfile
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+type Entry struct {
+	Name    string
+	Command string
+}
+
+var entryRegex = regexp.MustCompile(`^([A-Za-z0-9_.-]+):\s*(.+)$`)
+
+// Parse parses Procfile from r, empty lines, and lines starting with # are skipped
+func Parse(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+	seen := map[string]struct{}{}
+
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		m := entryRegex.FindStringSubmatch(line)
+		if m == nil {
+			return nil, fmt.Errorf("line %d: invalid entry (%s), must be of form <name>: <command>", lineNo, line)
+		}
+
+		if _, ok := seen[m[1]]; ok {
+			return nil, fmt.Errorf("line %d: duplicate entry (%s)", lineNo, m[1])
+		}
+		seen[m[1]] = struct{}{}
+
+		entries = append(entries, Entry{Name: m[1], Command: strings.TrimSpace(m[2])})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// ParseFile parses Procfile at path
+func ParseFile(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	entries, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("procfile (%s): %w", path, err)
+	}
+	return entries, nil
+}
@@ -0,0 +1,62 @@
+package procfile
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_Parse(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []Entry
+		wantErr bool
+	}{
+		{
+			name:  "1. entries",
+			input: "web: go run ./cmd/api\nworker:go run ./cmd/worker --queue=default\n",
+			want: []Entry{
+				{Name: "web", Command: "go run ./cmd/api"},
+				{Name: "worker", Command: "go run ./cmd/worker --queue=default"},
+			},
+		},
+		{
+			name:  "2. comments and empty lines",
+			input: "# processes\n\nweb: go run ./cmd/api\n   \n",
+			want: []Entry{
+				{Name: "web", Command: "go run ./cmd/api"},
+			},
+		},
+		{
+			name:  "3. command with colons",
+			input: "web: PORT=8080 ./bin/api --addr=:8080",
+			want: []Entry{
+				{Name: "web", Command: "PORT=8080 ./bin/api --addr=:8080"},
+			},
+		},
+		{
+			name:    "4. invalid entry",
+			input:   "go run ./cmd/api",
+			wantErr: true,
+		},
+		{
+			name:    "5. duplicate entry",
+			input:   "web: a\nweb: b",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(strings.NewReader(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FAILED (%s)\n\t got err: %v\n\twant err: %v\n", tt.name, err, tt.wantErr)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FAILED (%s)\n\t got: %+v\n\twant: %+v\n", tt.name, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package watcher
+
+import (
+	"context"
+	"sort"
+)
+
+// Diagnosis is a report of what a watcher (with same args) would watch, along with the inotify limits
+type Diagnosis struct {
+	// Directories is count of directories, that would be added to the watch list
+	Directories int
+	// WatchTargets are files, and paths that do not exist yet
+	WatchTargets []string
+	// IgnoredDirs are directories skipped, as they are in the ignore list
+	IgnoredDirs []string
+
+	// Limits and Usage are nil, on systems without inotify
+	Limits *InotifyLimits
+	Usage  *InotifyUsage
+}
+
+// WithinLimits tells whether directories to be watched fit in the inotify watch limit
+func (d *Diagnosis) WithinLimits() bool {
+	if d.Limits == nil || d.Usage == nil {
+		return true
+	}
+	return d.Usage.Watches+d.Directories+len(d.WatchTargets) <= d.Limits.MaxUserWatches
+}
+
+// RaiseLimitHint returns an actionable message, to raise the inotify watch limit
+func (d *Diagnosis) RaiseLimitHint() string {
+	required := d.Directories + len(d.WatchTargets)
+	if d.Usage != nil {
+		required += d.Usage.Watches
+	}
+	return raiseLimitHint("max_user_watches", required)
+}
+
+// Diagnose walks watch dirs the way NewWatcher does, without watching anything
+func Diagnose(args WatcherArgs) (*Diagnosis, error) {
+	fsw, watchDirs, err := newWatcher(context.TODO(), args)
+	if err != nil {
+		return nil, err
+	}
+
+	walked, err := fsw.walkWatchDirs(watchDirs...)
+	if err != nil {
+		return nil, err
+	}
+
+	d := Diagnosis{
+		Directories: len(walked),
+		IgnoredDirs: fsw.ignoredDirs,
+	}
+
+	for target := range fsw.watchTargets {
+		d.WatchTargets = append(d.WatchTargets, target)
+	}
+	sort.Strings(d.WatchTargets)
+
+	if limits, err := ReadInotifyLimits(); err == nil {
+		d.Limits = limits
+		d.Usage, _ = ReadInotifyUsage()
+	}
+
+	return &d, nil
+}
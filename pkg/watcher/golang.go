@@ -0,0 +1,121 @@
+package watcher
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+
+	"github.com/nxtcoder17/fwatcher/pkg/godeps"
+)
+
+// goMode watches only those files, that a go package depends upon
+type goMode struct {
+	pkg   string
+	graph *godeps.Graph
+
+	refresh chan struct{}
+}
+
+// goWatchDirs are directories of a go dependency graph, only files directly inside them are watched
+func goWatchDirs(graph *godeps.Graph) []WatchDir {
+	dirs := make([]WatchDir, 0, len(graph.Dirs))
+	for dir := range graph.Dirs {
+		dirs = append(dirs, WatchDir{Path: dir, MaxDepth: 1})
+	}
+	return dirs
+}
+
+// inGoGraph tells whether a file lies in a directory of go dependency graph (ok), and whether it is part of the graph
+func (f *Watcher) inGoGraph(name string) (contains bool, ok bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.golang.graph.Dirs[filepath.Dir(name)]; !ok {
+		return false, false
+	}
+	return f.golang.graph.Contains(name), true
+}
+
+// newlyEmbedded tells whether a file is embedded (by //go:embed) in go package, but is not in go dependency graph yet
+func (f *Watcher) newlyEmbedded(name string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	_, ok := f.golang.graph.Files[name]
+	return !ok && f.golang.graph.Embeds(name)
+}
+
+// requestGoGraphRefresh asks for go dependency graph to be refreshed, if the changed file could have altered it
+func (f *Watcher) requestGoGraphRefresh(name string) {
+	if f.golang == nil {
+		return
+	}
+
+	switch base := filepath.Base(name); {
+	case base == "go.mod", base == "go.sum", base == "go.work":
+	case strings.HasSuffix(base, ".go") && !strings.HasSuffix(base, "_test.go"):
+	case f.newlyEmbedded(name):
+	default:
+		return
+	}
+
+	select {
+	case f.golang.refresh <- struct{}{}:
+	default:
+		// INFO: a refresh is already pending
+	}
+}
+
+// refreshGoGraph re-computes go dependency graph on request, and updates the watch list as per it
+func (f *Watcher) refreshGoGraph(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-f.golang.refresh:
+		}
+
+		graph, err := godeps.Load(ctx, "", f.golang.pkg)
+		if err != nil {
+			f.Logger.Warn("failed to refresh go dependency graph, continuing with the previous one", "err", err)
+			continue
+		}
+
+		f.mu.Lock()
+		prev := f.golang.graph
+		f.golang.graph = graph
+		f.mu.Unlock()
+
+		added, removed := 0, 0
+		for dir := range graph.Dirs {
+			if _, ok := prev.Dirs[dir]; !ok {
+				f.addDirs(0, dir)
+				added++
+			}
+		}
+
+		for dir := range prev.Dirs {
+			if _, ok := graph.Dirs[dir]; !ok {
+				f.unwatchDir(dir)
+				removed++
+			}
+		}
+
+		if f.shouldLogWatchEvents || added+removed > 0 {
+			f.Logger.Debug("refreshed go dependency graph", "package", f.golang.pkg, "files", len(graph.Files), "dirs.added", added, "dirs.removed", removed)
+		}
+	}
+}
+
+// unwatchDir removes a directory from the watch list
+func (f *Watcher) unwatchDir(dir string) {
+	if err := f.watcher.Remove(dir); err != nil {
+		f.Logger.Debug("failed to remove from watchlist", "dir", dir, "err", err)
+		return
+	}
+	f.forgetDir(dir)
+
+	f.mu.Lock()
+	f.directoryCount--
+	f.mu.Unlock()
+}
@@ -0,0 +1,137 @@
+package watcher
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func Test_Watcher_GoPackage(t *testing.T) {
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mainGo := func(imports ...string) string {
+		s := "package main\n\nimport (\n\t\"embed\"\n"
+		for _, imp := range imports {
+			s += "\t_ \"example.com/app/" + imp + "\"\n"
+		}
+		return s + ")\n\n//go:embed assets\nvar assets embed.FS\n\nfunc main() {}\n"
+	}
+
+	writeFile := func(t *testing.T, name string, content string) {
+		p := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeFile(t, "go.mod", "module example.com/app\n\ngo 1.22\n")
+	writeFile(t, "main.go", mainGo())
+	writeFile(t, "notes.txt", "notes")
+	writeFile(t, ".env", "A=1")
+	writeFile(t, "assets/app.css", "body {}")
+	writeFile(t, "extra/extra.go", "package extra\n")
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(root); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	// INFO: like --go . --env-file .env
+	w, cf := newTestWatcher(t, WatcherArgs{GoPackage: ".", WatchDirs: []string{filepath.Join(root, ".env")}})
+	defer cf()
+
+	// written writes a file, and tells whether it resulted in an event for it
+	written := func(t *testing.T, name string, content string) bool {
+		writeFile(t, name, content)
+
+		got := false
+		for {
+			ev, ok := waitForEvent(t, w, 300*time.Millisecond)
+			if !ok {
+				return got
+			}
+			if ev.Name == filepath.Join(root, name) {
+				got = true
+			}
+		}
+	}
+
+	// eventually tells whether writes to a file, end up resulting (or, not resulting) in events, as go dependency graph
+	// gets refreshed
+	eventually := func(t *testing.T, name string, want bool) bool {
+		for i := 0; i < 20; i++ {
+			if written(t, name, "package extra\n") == want {
+				return true
+			}
+		}
+		return false
+	}
+
+	t.Run("1. filters events, by go dependency graph", func(t *testing.T) {
+		tests := []struct {
+			file    string
+			content string
+			want    bool
+		}{
+			{file: "main.go", content: mainGo(), want: true},
+			{file: "notes.txt", content: "more notes", want: false},
+			{file: "main_test.go", content: "package main\n", want: false},
+
+			// INFO: files being explicitly watched, are not filtered by the graph
+			{file: ".env", content: "A=2", want: true},
+			{file: "assets/app.css", content: "body { margin: 0 }", want: true},
+
+			// INFO: new files in embedded directories are part of the graph
+			{file: "assets/new.css", content: "body {}", want: true},
+			{file: "assets/.new.css", content: "body {}", want: false},
+		}
+
+		for _, tt := range tests {
+			if got := written(t, tt.file, tt.content); got != tt.want {
+				t.Errorf("FAILED (%s)\n\t got: %v\n\twant: %v\n", tt.file, got, tt.want)
+			}
+		}
+	})
+
+	t.Run("2. refreshes go dependency graph, with new embedded files", func(t *testing.T) {
+		for i := 0; i < 20; i++ {
+			w.mu.Lock()
+			_, ok := w.golang.graph.Files[filepath.Join(root, "assets", "new.css")]
+			w.mu.Unlock()
+			if ok {
+				return
+			}
+			<-time.After(100 * time.Millisecond)
+		}
+		t.Errorf("FAILED\n\t got: assets/new.css not in go dependency graph\n\twant: assets/new.css in it\n")
+	})
+
+	t.Run("3. watches packages, once they are imported", func(t *testing.T) {
+		if written(t, "extra/extra.go", "package extra\n") {
+			t.Fatalf("FAILED\n\t got: event for extra/extra.go, before it is imported\n\twant: no event\n")
+		}
+
+		written(t, "main.go", mainGo("extra"))
+		if !eventually(t, "extra/extra.go", true) {
+			t.Errorf("FAILED\n\t got: no event for extra/extra.go, after it is imported\n\twant: an event\n")
+		}
+	})
+
+	t.Run("4. unwatches packages, once they are not imported anymore", func(t *testing.T) {
+		written(t, "main.go", mainGo())
+		if !eventually(t, "extra/extra.go", false) {
+			t.Errorf("FAILED\n\t got: event for extra/extra.go, after it is not imported anymore\n\twant: no event\n")
+		}
+	})
+}
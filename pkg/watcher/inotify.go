@@ -0,0 +1,153 @@
+package watcher
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+/*
+inotify (on linux) limits, how many directories (max_user_watches) can be watched, and how many
+watchers (max_user_instances) can be created by a user. Once those limits are reached, directories
+silently stop being watched, so these helpers are here to diagnose it.
+*/
+
+const inotifyProcDir = "/proc/sys/fs/inotify"
+
+// InotifyLimits are inotify limits, configured for the current user
+type InotifyLimits struct {
+	MaxUserWatches   int
+	MaxUserInstances int
+}
+
+// InotifyUsage is inotify usage, across all processes of the current user
+type InotifyUsage struct {
+	Watches   int
+	Instances int
+}
+
+// ReadInotifyLimits reads inotify limits from /proc, it fails on systems without inotify
+func ReadInotifyLimits() (*InotifyLimits, error) {
+	watches, err := readProcInt(filepath.Join(inotifyProcDir, "max_user_watches"))
+	if err != nil {
+		return nil, err
+	}
+
+	instances, err := readProcInt(filepath.Join(inotifyProcDir, "max_user_instances"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &InotifyLimits{MaxUserWatches: watches, MaxUserInstances: instances}, nil
+}
+
+// ReadInotifyUsage counts inotify instances, and their watches, by going through file descriptors of every process it can read
+func ReadInotifyUsage() (*InotifyUsage, error) {
+	procs, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil, err
+	}
+
+	uid := os.Getuid()
+
+	usage := InotifyUsage{}
+	for _, proc := range procs {
+		if _, err := strconv.Atoi(proc.Name()); err != nil {
+			continue
+		}
+
+		if info, err := proc.Info(); err != nil || !isOwnedBy(info, uid) {
+			continue
+		}
+
+		fds, err := os.ReadDir(filepath.Join("/proc", proc.Name(), "fd"))
+		if err != nil {
+			// INFO: process has exited, or we are not allowed to read it
+			continue
+		}
+
+		for _, fd := range fds {
+			link, err := os.Readlink(filepath.Join("/proc", proc.Name(), "fd", fd.Name()))
+			if err != nil || link != "anon_inode:inotify" {
+				continue
+			}
+
+			usage.Instances++
+			usage.Watches += countInotifyWatches(filepath.Join("/proc", proc.Name(), "fdinfo", fd.Name()))
+		}
+	}
+
+	return &usage, nil
+}
+
+func isOwnedBy(info fs.FileInfo, uid int) bool {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	return ok && int(st.Uid) == uid
+}
+
+func countInotifyWatches(fdinfo string) int {
+	f, err := os.Open(fdinfo)
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+
+	count := 0
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if strings.HasPrefix(sc.Text(), "inotify wd:") {
+			count++
+		}
+	}
+	return count
+}
+
+func readProcInt(p string) (int, error) {
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(b)))
+}
+
+// raiseLimitHint returns an actionable message, to raise an inotify limit (max_user_watches, or max_user_instances), so that required fits in it.
+// With required being 0, current limit gets doubled
+func raiseLimitHint(limit string, required int) string {
+	value := required * 2
+	if current, err := readProcInt(filepath.Join(inotifyProcDir, limit)); err == nil && value <= current {
+		value = current * 2
+	}
+
+	return fmt.Sprintf("raise it with `sudo sysctl fs.inotify.%s=%d` (and add `fs.inotify.%s=%d` to /etc/sysctl.conf, to persist it)", limit, value, limit, value)
+}
+
+// preflight checks whether count more watches fit in the inotify limits, and warns with a way to raise those limits if they don't
+func (f *Watcher) preflight(count int) {
+	limits, err := ReadInotifyLimits()
+	if err != nil {
+		// INFO: not on linux, there is nothing to check
+		return
+	}
+
+	usage, err := ReadInotifyUsage()
+	if err != nil {
+		usage = &InotifyUsage{}
+	}
+
+	if f.shouldLogWatchEvents {
+		f.Logger.Debug("inotify preflight", "directories", count, "watches.used", usage.Watches, "watches.limit", limits.MaxUserWatches, "instances.used", usage.Instances, "instances.limit", limits.MaxUserInstances)
+	}
+
+	if required := usage.Watches + count; required > limits.MaxUserWatches {
+		f.Logger.Warn(fmt.Sprintf("inotify watch limit is too low, %d directories need to be watched, %d watches are already in use, while limit is %d. Changes in some directories will be missed, %s", count, usage.Watches, limits.MaxUserWatches, raiseLimitHint("max_user_watches", required)))
+	}
+
+	if usage.Instances >= limits.MaxUserInstances {
+		f.Logger.Warn(fmt.Sprintf("inotify instance limit (%d) has been reached, new watchers will fail to start, %s", limits.MaxUserInstances, raiseLimitHint("max_user_instances", usage.Instances+1)))
+	}
+}
@@ -86,3 +86,39 @@ func (f *Watcher) WatchAndExecute(ctx context.Context, executors []executor.Exec
 
 	return nil
 }
+
+// ExecuteUntilSuccess starts executor, and re-starts it on every watch event, until it succeeds.
+// It returns ctx.Err(), if ctx got cancelled before that
+func (f *Watcher) ExecuteUntilSuccess(ctx context.Context, ex executor.Executor) error {
+	ctx, cf := context.WithCancel(ctx)
+	defer cf()
+
+	go func() {
+		<-ctx.Done()
+		ex.Stop()
+	}()
+
+	go f.Watch(ctx)
+
+	counter := 0
+	for {
+		err := ex.Start()
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if err == nil {
+			return nil
+		}
+
+		f.Logger.Info("[WAITING] for changes, to run again", "err", err)
+
+		event, ok := <-f.GetEvents()
+		if !ok {
+			return err
+		}
+
+		counter += 1
+		f.Logger.Info(fmt.Sprintf("[RERUNNING (%d)] due changes in %s", counter, event.Name))
+	}
+}
@@ -3,10 +3,14 @@ package watcher
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"io"
 	"log/slog"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -520,3 +524,72 @@ func Test_Watcher_WatchAndExecute(t *testing.T) {
 		})
 	}
 }
+
+// failingExecutor fails its first `failures` starts
+type failingExecutor struct {
+	failures int32
+	starts   atomic.Int32
+}
+
+func (e *failingExecutor) OnWatchEvent(ev executor.Event) error { return nil }
+func (e *failingExecutor) Stop() error                          { return nil }
+
+func (e *failingExecutor) Start() error {
+	if e.starts.Add(1) <= e.failures {
+		return fmt.Errorf("failed")
+	}
+	return nil
+}
+
+var _ executor.Executor = (*failingExecutor)(nil)
+
+func Test_Watcher_ExecuteUntilSuccess(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures int32
+	}{
+		{name: "1. succeeds right away", failures: 0},
+		{name: "2. succeeds after a few changes", failures: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+
+			cooldown := 0 * time.Millisecond
+			w, err := NewWatcher(context.TODO(), WatcherArgs{WatchDirs: []string{root}, CooldownDuration: &cooldown})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx, cf := context.WithTimeout(context.TODO(), 5*time.Second)
+			defer cf()
+
+			ex := &failingExecutor{failures: tt.failures}
+
+			done := make(chan error, 1)
+			go func() {
+				done <- w.ExecuteUntilSuccess(ctx, ex)
+			}()
+
+			// INFO: keeps on changing a file, until executor succeeds
+			for {
+				select {
+				case err := <-done:
+					if err != nil {
+						t.Fatalf("FAILED (%s)\n\t got: %v\n\twant: <nil>\n", tt.name, err)
+					}
+
+					if got, want := ex.starts.Load(), tt.failures+1; got != want {
+						t.Errorf("FAILED (%s)\n\t got: %d starts\n\twant: %d starts\n", tt.name, got, want)
+					}
+					return
+				case <-time.After(100 * time.Millisecond):
+					if err := os.WriteFile(filepath.Join(root, "main.go"), []byte("package main"), 0o644); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+		})
+	}
+}
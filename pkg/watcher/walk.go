@@ -0,0 +1,226 @@
+package watcher
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+)
+
+// walkConcurrency is the number of directories, that are read concurrently while walking
+var walkConcurrency = min(4*runtime.GOMAXPROCS(0), 64)
+
+type walkItem struct {
+	dir   string
+	depth int
+	root  bool
+}
+
+// walkedDir is a directory, that needs to be added to the watch list, path is what gets added (i.e. real path of a
+// followed symlink), and depth is how many levels of sub-directories are to be watched below it
+type walkedDir struct {
+	dir   string
+	path  string
+	depth int
+}
+
+// walkDirs walks directories, along with their sub-directories up to depth levels below them (-1 being unlimited),
+// and returns the ones that need to be added to the watch list. Directories are read concurrently by a bounded pool of workers.
+func (f *Watcher) walkDirs(depth int, dirs ...string) ([]walkedDir, error) {
+	var (
+		mu   sync.Mutex
+		cond = sync.NewCond(&mu)
+
+		queue []walkItem
+		// pending is count of items that are either queued, or being walked
+		pending int
+		// visited are directories queued in this walk, so that none of them gets walked twice
+		visited = map[string]struct{}{}
+
+		walked  []walkedDir
+		walkErr error
+	)
+
+	for _, dir := range dirs {
+		if _, ok := visited[filepath.Clean(dir)]; ok {
+			continue
+		}
+		visited[filepath.Clean(dir)] = struct{}{}
+		queue = append(queue, walkItem{dir: dir, depth: depth, root: true})
+	}
+	pending = len(queue)
+
+	var wg sync.WaitGroup
+	for range walkConcurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for {
+				mu.Lock()
+				for len(queue) == 0 && pending > 0 {
+					cond.Wait()
+				}
+
+				if pending == 0 {
+					mu.Unlock()
+					return
+				}
+
+				item := queue[len(queue)-1]
+				queue = queue[:len(queue)-1]
+				mu.Unlock()
+
+				watchPath, children, err := f.walkDir(item)
+
+				mu.Lock()
+				if watchPath != "" {
+					walked = append(walked, walkedDir{dir: filepath.Clean(item.dir), path: watchPath, depth: item.depth})
+				}
+				if err != nil && item.root && walkErr == nil {
+					// INFO: like before, only failures of reading the directories asked for are reported
+					walkErr = err
+				}
+				for _, child := range children {
+					if _, ok := visited[child.dir]; ok {
+						continue
+					}
+					visited[child.dir] = struct{}{}
+					queue = append(queue, child)
+					pending++
+				}
+				pending--
+				mu.Unlock()
+
+				// INFO: wakes up idle workers, either for new children, or for exiting once everything has been walked
+				cond.Broadcast()
+			}
+		}()
+	}
+
+	wg.Wait()
+	return walked, walkErr
+}
+
+// walkDir checks a single directory, and returns the path to add to the watch list (empty, if it is not to be watched),
+// along with its sub-directories that need to be walked. Ignore rules are applied before descending into sub-directories.
+// Directories already being watched are skipped, while the directory itself gets recorded as being watched, only once
+// it has been added to the watch list (see watchDir)
+func (f *Watcher) walkDir(item walkItem) (string, []walkItem, error) {
+	dir := filepath.Clean(item.dir)
+
+	f.mu.Lock()
+	_, ok := f.watchingDirs[dir]
+	f.mu.Unlock()
+	if ok {
+		return "", nil, nil
+	}
+
+	fi, err := os.Lstat(dir)
+	if err != nil {
+		// INFO: instead of returning and error, seems like ignore is a better choice
+		return "", nil, nil
+	}
+
+	watchPath := dir
+	if f.followSymlinks {
+		realPath, ok := f.resolveSymlinks(dir)
+		if !ok {
+			return "", nil, nil
+		}
+
+		if fi, err = os.Stat(realPath); err != nil {
+			return "", nil, nil
+		}
+		watchPath = realPath
+	}
+
+	if !fi.IsDir() || f.isExcluded(dir) {
+		f.forgetRealDir(dir)
+		return "", nil, nil
+	}
+
+	if item.depth == 0 {
+		return watchPath, nil, nil
+	}
+
+	ls, err := os.ReadDir(dir)
+	if err != nil {
+		return watchPath, nil, err
+	}
+
+	children := make([]walkItem, 0, len(ls))
+	for _, l := range ls {
+		if !l.IsDir() && !(f.followSymlinks && l.Type()&os.ModeSymlink != 0) {
+			continue
+		}
+
+		child := filepath.Join(dir, l.Name())
+		if f.isExcluded(child) {
+			continue
+		}
+
+		children = append(children, walkItem{dir: child, depth: childDepth(item.depth)})
+	}
+
+	return watchPath, children, nil
+}
+
+// watchDir adds a walked directory to the watch list, and records it as being watched, once it has been added. A
+// directory that could not be added, is left unrecorded, so that it gets walked (and added) again by later walks
+func (f *Watcher) watchDir(d walkedDir) error {
+	f.mu.Lock()
+	_, watching := f.watchingDirs[d.dir]
+	_, adding := f.addingDirs[d.dir]
+	if !watching && !adding {
+		f.addingDirs[d.dir] = struct{}{}
+	}
+	f.mu.Unlock()
+	if watching || adding {
+		// INFO: another walk has got to it first
+		return nil
+	}
+
+	err := f.addToWatchList(d.path)
+
+	f.mu.Lock()
+	delete(f.addingDirs, d.dir)
+	if err == nil {
+		f.watchingDirs[d.dir] = d.depth
+	}
+	f.mu.Unlock()
+
+	if err != nil {
+		f.forgetRealDir(d.dir)
+	}
+	return err
+}
+
+// forgetRealDir forgets the real path, that dir got resolved to while walking, as dir is not going to be watched
+func (f *Watcher) forgetRealDir(dir string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for realPath, k := range f.realDirs {
+		if k == dir {
+			delete(f.realDirs, realPath)
+			delete(f.symlinkedDirs, realPath)
+		}
+	}
+}
+
+// isExcluded tells whether a directory is in the ignore list, and records it as an ignored directory if so
+func (f *Watcher) isExcluded(dir string) bool {
+	if _, ok := f.ExcludeDirs[filepath.Base(dir)]; !ok {
+		return false
+	}
+
+	if f.shouldLogWatchEvents {
+		f.Logger.Debug("EXCLUDED from watchlist", "dir", dir)
+	}
+
+	f.mu.Lock()
+	f.ignoredDirs = append(f.ignoredDirs, dir)
+	f.mu.Unlock()
+	return true
+}
@@ -2,27 +2,61 @@ package watcher
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
+	"github.com/nxtcoder17/fwatcher/pkg/godeps"
 )
 
 type Watcher struct {
 	watcher *fsnotify.Watcher
 
+	// mu guards directory bookkeeping below, as directories are walked concurrently, and in background of Watch
+	mu sync.Mutex
+	// ready is closed, once initial walk of watch dirs has completed
+	ready chan struct{}
+
 	directoryCount int
+	// failedCount is count of directories, that could not be added to the watch list
+	failedCount int
 
 	Logger         *slog.Logger
 	OnlySuffixes   []string
 	IgnoreSuffixes []string
 
-	ExcludeDirs  map[string]struct{}
-	watchingDirs map[string]struct{}
+	ExcludeDirs map[string]struct{}
+	// roots are (absolute) paths of watch dirs, exclusions apply only to paths below them
+	roots []string
+	// ignoredDirs are directories, that were skipped while walking as they are in ExcludeDirs
+	ignoredDirs []string
+	// watchingDirs maps directories being watched, to how many levels of sub-directories are to be watched below them (-1 being unlimited)
+	watchingDirs map[string]int
+	// addingDirs are directories, that are being added to the watch list, see watchDir
+	addingDirs map[string]struct{}
+
+	// watchTargets are files, or paths that do not exist yet, which are watched via their nearest existing parent directory,
+	// and parentDirs are those parent directories, events from them are only processed for watch targets
+	watchTargets map[string]int
+	parentDirs   map[string]struct{}
+
+	// followSymlinks makes symlinked directories watchable, their targets are watched only once,
+	// and symlinkedDirs maps those real targets back to the path (via symlink) user sees
+	followSymlinks bool
+	realDirs       map[string]string
+	symlinkedDirs  map[string]string
+
+	// golang (when watching a go package) restricts events to files, that the package depends upon
+	golang *goMode
 
 	cooldownDuration time.Duration
 
@@ -46,7 +80,7 @@ var (
 	Chmod  = fsnotify.Chmod
 )
 
-func (f Watcher) ignoreEvent(event fsnotify.Event) (ignore bool, reason string) {
+func (f *Watcher) ignoreEvent(event fsnotify.Event) (ignore bool, reason string) {
 	// INFO: any file change emits a chain of events, but
 	// we can always expect a Write event out of that event chain
 	if event.Op != fsnotify.Write {
@@ -67,12 +101,27 @@ func (f Watcher) ignoreEvent(event fsnotify.Event) (ignore bool, reason string)
 		return true, "event is from a special file from vim/neovim which ends in ~"
 	}
 
-	for k := range f.ExcludeDirs {
-		if strings.Contains(event.Name, k) {
-			return true, "event is generating from an excluded path"
+	f.mu.Lock()
+	_, isTarget := f.watchTargets[event.Name]
+	f.mu.Unlock()
+	if isTarget {
+		return false, "event is from a file, that is being explicitly watched"
+	}
+
+	if f.golang != nil {
+		// INFO: events from other watch dirs, are filtered as usual
+		if contains, ok := f.inGoGraph(event.Name); ok {
+			if !contains {
+				return true, fmt.Sprintf("event is from a file, that go package (%s) does not depend upon", f.golang.pkg)
+			}
+			return false, fmt.Sprintf("event is from a file, that go package (%s) depends upon", f.golang.pkg)
 		}
 	}
 
+	if f.excluded(event.Name) {
+		return true, "event is generating from an excluded path"
+	}
+
 	for _, suffix := range f.IgnoreSuffixes {
 		if strings.HasSuffix(event.Name, suffix) {
 			return true, fmt.Sprintf("because, file has suffix (%s), which is in ignore suffixes array(%+v)", suffix, f.IgnoreSuffixes)
@@ -100,6 +149,10 @@ func (f Watcher) ignoreEvent(event fsnotify.Event) (ignore bool, reason string)
 func (f *Watcher) Watch(ctx context.Context) {
 	lastProcessingTime := time.Now()
 
+	if f.golang != nil {
+		go f.refreshGoGraph(ctx)
+	}
+
 	for {
 		select {
 		case event, ok := <-f.watcher.Events:
@@ -108,51 +161,46 @@ func (f *Watcher) Watch(ctx context.Context) {
 					return
 				}
 
-				if event.Op == fsnotify.Create {
-					fi, _ := os.Stat(event.Name)
-					if fi != nil && fi.IsDir() {
-						skip := false
+				event.Name = f.visiblePath(event.Name)
+				events := []fsnotify.Event{event}
 
-						for k := range f.ExcludeDirs {
-							if strings.Contains(event.Name, k) {
-								skip = true
-								break
-							}
-						}
+				f.requestGoGraphRefresh(event.Name)
 
-						if !skip {
-							f.RecursiveAdd(event.Name)
-						}
-					}
+				if event.Op.Has(fsnotify.Remove) || event.Op.Has(fsnotify.Rename) {
+					f.forgetDir(event.Name)
 				}
 
-				t := time.Now()
-				if f.shouldLogWatchEvents {
-					f.Logger.Debug(fmt.Sprintf("event %+v received", event))
+				if event.Op.Has(fsnotify.Create) {
+					// INFO: a directory on the way to a watch target has been created, so target's watch needs to move closer to it
+					events = append(events, f.upgradeWatchTargets(event.Name)...)
 				}
 
-				if ignore, reason := f.ignoreEvent(event); ignore {
+				watched := f.isWatched(event.Name)
+				if !watched {
 					if f.shouldLogWatchEvents {
-						f.Logger.Debug("IGNORING", "event.name", event.Name, "reason", reason)
+						f.Logger.Debug("IGNORING", "event.name", event.Name, "reason", "event is from a parent directory, of a watched file")
 					}
-					continue
+					events = events[1:]
 				}
 
-				if f.shouldLogWatchEvents {
-					f.Logger.Debug("PROCESSING", "event.name", event.Name, "event.op", event.Op.String())
-				}
+				if watched && event.Op == fsnotify.Create {
+					fi, _ := os.Stat(event.Name)
+					if fi != nil && fi.IsDir() {
+						if depth, ok := f.depthFor(event.Name); ok && !f.excluded(event.Name) {
+							f.addDirs(depth, event.Name)
 
-				if time.Since(lastProcessingTime) < f.cooldownDuration {
-					if f.shouldLogWatchEvents {
-						f.Logger.Debug(fmt.Sprintf("too many events under %s, ignoring...", f.cooldownDuration.String()), "event.name", event.Name)
+							// INFO: files created inside this directory before its watch got registered, would never
+							// emit events, so we synthesize them from whatever is already present on the disk
+							events = append(events, f.synthesizeEvents(event.Name, depth)...)
+						}
 					}
-					continue
 				}
 
-				f.eventsCh <- Event(event)
-
-				if f.shouldLogWatchEvents {
-					f.Logger.Debug("watch loop completed", "took", fmt.Sprintf("%dms", time.Since(t).Milliseconds()))
+				for _, ev := range events {
+					if f.processEvent(ev, lastProcessingTime) {
+						// INFO: one event is enough to trigger a reload, rest of the synthesized events are redundant
+						break
+					}
 				}
 			}
 
@@ -167,61 +215,415 @@ func (f *Watcher) Watch(ctx context.Context) {
 	}
 }
 
-func (f *Watcher) RecursiveAdd(dirs ...string) error {
-	for _, dir := range dirs {
-		if _, ok := f.watchingDirs[dir]; ok {
-			continue
+// excluded tells whether path lies in an excluded directory, i.e. whether any of its components below its watch root, is
+// in ExcludeDirs. Directories above the watch root (like, me.github.io for .git) are not for fwatcher to exclude
+func (f *Watcher) excluded(path string) bool {
+	root := ""
+	for _, r := range f.roots {
+		if (path == r || strings.HasPrefix(path, r+string(filepath.Separator))) && len(r) > len(root) {
+			root = r
 		}
+	}
 
-		f.watchingDirs[dir] = struct{}{}
+	rel := filepath.Base(path)
+	if root != "" {
+		rel, _ = filepath.Rel(root, path)
+	}
 
-		fi, err := os.Lstat(dir)
-		if err != nil {
+	parts := strings.Split(rel, string(filepath.Separator))
+	for k := range f.ExcludeDirs {
+		if !strings.ContainsRune(filepath.Clean(k), filepath.Separator) {
+			if slices.Contains(parts, k) {
+				return true
+			}
 			continue
-			// INFO: instead of returning and error, seems like ignore is a better choice
-			// return err
 		}
 
-		if !fi.IsDir() {
+		// INFO: an excluded path (like, ./vendor/cache), is relative to the watch root
+		if k := filepath.Clean(k); rel == k || strings.HasPrefix(rel, k+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// processEvent filters the event, and forwards it to the events channel, it returns true if the event was forwarded
+func (f *Watcher) processEvent(event fsnotify.Event, lastProcessingTime time.Time) bool {
+	t := time.Now()
+	if f.shouldLogWatchEvents {
+		f.Logger.Debug(fmt.Sprintf("event %+v received", event))
+	}
+
+	if ignore, reason := f.ignoreEvent(event); ignore {
+		if f.shouldLogWatchEvents {
+			f.Logger.Debug("IGNORING", "event.name", event.Name, "reason", reason)
+		}
+		return false
+	}
+
+	if f.shouldLogWatchEvents {
+		f.Logger.Debug("PROCESSING", "event.name", event.Name, "event.op", event.Op.String())
+	}
+
+	if time.Since(lastProcessingTime) < f.cooldownDuration {
+		if f.shouldLogWatchEvents {
+			f.Logger.Debug(fmt.Sprintf("too many events under %s, ignoring...", f.cooldownDuration.String()), "event.name", event.Name)
+		}
+		return false
+	}
+
+	f.eventsCh <- Event(event)
+
+	if f.shouldLogWatchEvents {
+		f.Logger.Debug("watch loop completed", "took", fmt.Sprintf("%dms", time.Since(t).Milliseconds()))
+	}
+	return true
+}
+
+// synthesizeEvents walks a newly created directory (up to depth levels of sub-directories), and creates WRITE events for files that already exist in it
+func (f *Watcher) synthesizeEvents(dir string, depth int) []fsnotify.Event {
+	ls, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	var events []fsnotify.Event
+	for _, l := range ls {
+		p := filepath.Join(dir, l.Name())
+		if l.IsDir() {
+			if _, ok := f.ExcludeDirs[l.Name()]; ok || depth == 0 {
+				continue
+			}
+			events = append(events, f.synthesizeEvents(p, childDepth(depth))...)
 			continue
 		}
 
-		if _, ok := f.ExcludeDirs[filepath.Base(dir)]; ok {
-			if f.shouldLogWatchEvents {
-				f.Logger.Debug("EXCLUDED from watchlist", "dir", dir)
+		if f.shouldLogWatchEvents {
+			f.Logger.Debug("SYNTHESIZED event", "file", p)
+		}
+		events = append(events, fsnotify.Event{Name: p, Op: fsnotify.Write})
+	}
+
+	return events
+}
+
+// RecursiveAdd adds directories, and all of their sub-directories to the watch list
+func (f *Watcher) RecursiveAdd(dirs ...string) error {
+	return f.addDirs(-1, dirs...)
+}
+
+// addDirs adds directories to the watch list, along with their sub-directories up to depth levels below them (-1 being unlimited)
+func (f *Watcher) addDirs(depth int, dirs ...string) error {
+	walked, err := f.walkDirs(depth, dirs...)
+	for _, d := range walked {
+		f.watchDir(d)
+	}
+	return err
+}
+
+// childDepth is the depth, sub-directories of a directory being watched with depth, are to be watched with
+func childDepth(depth int) int {
+	if depth < 0 {
+		return -1
+	}
+	return depth - 1
+}
+
+// depthFor returns the depth, a newly created directory is to be watched with, and false if it lies beyond the depth of its watch root
+func (f *Watcher) depthFor(dir string) (int, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if depth, ok := f.watchTargets[dir]; ok {
+		return depth, true
+	}
+
+	depth, ok := f.watchingDirs[filepath.Dir(dir)]
+	if !ok {
+		return -1, true
+	}
+
+	if depth == 0 {
+		if f.shouldLogWatchEvents {
+			f.Logger.Debug("SKIPPED, directory is beyond max depth", "dir", dir)
+		}
+		return 0, false
+	}
+
+	return childDepth(depth), true
+}
+
+// resolveSymlinks resolves dir to its real path, and records it in the symlinked directories if it differs from dir.
+// It returns false, when dir can not be resolved, or when its real path is already being watched (which is also how symlink cycles are broken)
+func (f *Watcher) resolveSymlinks(dir string) (string, bool) {
+	absPath, err := filepath.Abs(dir)
+	if err != nil {
+		return "", false
+	}
+
+	realPath, err := filepath.EvalSymlinks(absPath)
+	if err != nil {
+		if f.shouldLogWatchEvents {
+			f.Logger.Debug("SKIPPED dangling symlink", "dir", dir, "err", err)
+		}
+		return "", false
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.realDirs[realPath]; ok {
+		if f.shouldLogWatchEvents {
+			reason := "target is already being watched"
+			if parent, err := filepath.EvalSymlinks(filepath.Dir(absPath)); err == nil && strings.HasPrefix(parent+string(filepath.Separator), realPath+string(filepath.Separator)) {
+				reason = "symlink cycle detected"
 			}
+			f.Logger.Debug("SKIPPED symlink", "dir", dir, "target", realPath, "reason", reason)
+		}
+		return "", false
+	}
+	f.realDirs[realPath] = dir
+
+	if realPath == absPath {
+		// INFO: not a symlink, keep watching it the way user specified it
+		return dir, true
+	}
+
+	f.symlinkedDirs[realPath] = dir
+	return realPath, true
+}
+
+// visiblePath maps a path inside a symlink target, back to the path (via symlink) that user is watching
+func (f *Watcher) visiblePath(name string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if len(f.symlinkedDirs) == 0 {
+		return name
+	}
+
+	if dir, ok := f.symlinkedDirs[name]; ok {
+		return dir
+	}
+
+	if dir, ok := f.symlinkedDirs[filepath.Dir(name)]; ok {
+		return filepath.Join(dir, filepath.Base(name))
+	}
+
+	return name
+}
+
+// addWatchDirs adds directories to the watch list (up to their max depth), while files and paths that do not exist yet, are watched as watch targets
+func (f *Watcher) addWatchDirs(dirs ...WatchDir) error {
+	walked, err := f.walkWatchDirs(dirs...)
+	if err != nil {
+		return err
+	}
+
+	f.preflight(len(walked) + len(f.targets()))
+
+	for _, d := range walked {
+		f.watchDir(d)
+	}
+
+	for target, depth := range f.targets() {
+		f.watchTarget(target, depth)
+	}
+
+	f.mu.Lock()
+	failedCount := f.failedCount
+	f.mu.Unlock()
+	if failedCount > 0 {
+		f.Logger.Error(fmt.Sprintf("%d directories could not be watched, changes in them will be missed", failedCount))
+	}
+
+	return nil
+}
+
+// walkWatchDirs walks directories (up to their max depth), and returns the ones that need to be added to the watch list,
+// while files and paths that do not exist yet, are recorded as watch targets
+func (f *Watcher) walkWatchDirs(dirs ...WatchDir) ([]walkedDir, error) {
+	var walked []walkedDir
+	seen := map[string]struct{}{}
+
+	for _, d := range dirs {
+		if strings.HasPrefix(filepath.Base(d.Path), "-") {
+			// INFO: it is an ignored directory
 			continue
 		}
 
-		f.addToWatchList(dir)
+		p := filepath.Clean(d.Path)
 
-		ls, err := os.ReadDir(dir)
-		if err != nil {
-			return err
+		depth := -1
+		if d.MaxDepth > 0 {
+			// INFO: max depth counts levels of files, and files in a directory are 1 level deep, so its sub-directories need not be watched
+			depth = d.MaxDepth - 1
 		}
 
-		de := make([]string, 0, len(ls))
-		for _, l := range ls { // TODO: use filepath.WalkDir
-			if !l.IsDir() {
-				continue
+		if fi, err := os.Stat(p); err == nil && fi.IsDir() {
+			dirs, err := f.walkDirs(depth, p)
+			if err != nil {
+				return nil, err
+			}
+			for _, d := range dirs {
+				// INFO: watch dirs may overlap (like, . and ./web)
+				if _, ok := seen[d.dir]; !ok {
+					seen[d.dir] = struct{}{}
+					walked = append(walked, d)
+				}
 			}
-			de = append(de, filepath.Join(dir, l.Name()))
+			continue
+		}
+
+		f.mu.Lock()
+		f.watchTargets[p] = depth
+		f.mu.Unlock()
+	}
+
+	return walked, nil
+}
+
+// watchTarget watches target (a file, or a path that does not exist yet) via its nearest existing parent directory,
+// if target already exists as a directory, it gets watched up to depth. It returns events synthesized for the target's contents.
+func (f *Watcher) watchTarget(target string, depth int) []fsnotify.Event {
+	f.mu.Lock()
+	f.watchTargets[target] = depth
+	f.mu.Unlock()
+
+	if fi, err := os.Stat(target); err == nil && fi.IsDir() {
+		f.addDirs(depth, target)
+		return f.synthesizeEvents(target, depth)
+	}
+
+	parent := filepath.Dir(target)
+	for {
+		if fi, err := os.Stat(parent); err == nil && fi.IsDir() {
+			break
 		}
 
-		f.RecursiveAdd(de...)
+		next := filepath.Dir(parent)
+		if next == parent {
+			f.Logger.Warn("no existing parent directory found, to watch", "path", target)
+			return nil
+		}
+		parent = next
+	}
+
+	f.mu.Lock()
+	_, isParent := f.parentDirs[parent]
+	_, isWatching := f.watchingDirs[parent]
+	f.parentDirs[parent] = struct{}{}
+	f.mu.Unlock()
+
+	if !isParent {
+		if !isWatching {
+			f.addToWatchList(parent)
+		}
+		if f.shouldLogWatchEvents {
+			f.Logger.Debug("WATCHING via parent directory", "path", target, "parent", parent)
+		}
+	}
+
+	if fi, err := os.Stat(target); err == nil && !fi.IsDir() {
+		return []fsnotify.Event{{Name: target, Op: fsnotify.Write}}
 	}
 
 	return nil
 }
 
+// targets returns a copy of watch targets, so that they can be iterated without holding the lock
+func (f *Watcher) targets() map[string]int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	targets := make(map[string]int, len(f.watchTargets))
+	for k, v := range f.watchTargets {
+		targets[k] = v
+	}
+	return targets
+}
+
+// upgradeWatchTargets re-watches targets lying under the newly created path, so that they are watched via their nearest existing parent
+func (f *Watcher) upgradeWatchTargets(created string) []fsnotify.Event {
+	var events []fsnotify.Event
+	for target, depth := range f.targets() {
+		if strings.HasPrefix(target, created+string(filepath.Separator)) {
+			events = append(events, f.watchTarget(target, depth)...)
+		}
+	}
+	return events
+}
+
+// isWatched tells whether an event for this path should be processed, which is false for
+// paths, in a parent directory (of a watch target) that is not being watched recursively
+func (f *Watcher) isWatched(name string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.watchTargets[name]; ok {
+		return true
+	}
+
+	parent := filepath.Dir(name)
+	if _, ok := f.parentDirs[parent]; !ok {
+		return true
+	}
+
+	_, ok := f.watchingDirs[parent]
+	return ok
+}
+
+// forgetDir removes a deleted directory (and its sub-directories) from the watch list, so that it gets watched again, when re-created
+func (f *Watcher) forgetDir(dir string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.watchingDirs[dir]; !ok {
+		return
+	}
+
+	prefix := dir + string(filepath.Separator)
+	for k := range f.watchingDirs {
+		if k == dir || strings.HasPrefix(k, prefix) {
+			delete(f.watchingDirs, k)
+		}
+	}
+
+	for realPath, k := range f.realDirs {
+		if k == dir || strings.HasPrefix(k, prefix) {
+			delete(f.realDirs, realPath)
+			delete(f.symlinkedDirs, realPath)
+		}
+	}
+
+	if f.shouldLogWatchEvents {
+		f.Logger.Debug("REMOVED from watchlist", "dir", dir)
+	}
+}
+
 func (f *Watcher) addToWatchList(dir string) error {
 	if err := f.watcher.Add(dir); err != nil {
+		f.mu.Lock()
+		f.failedCount++
+		failedCount := f.failedCount
+		f.mu.Unlock()
+
+		if errors.Is(err, syscall.ENOSPC) {
+			if failedCount == 1 {
+				f.Logger.Error(fmt.Sprintf("inotify watch limit reached, %s", raiseLimitHint("max_user_watches", 0)))
+			}
+			return err
+		}
 		f.Logger.Error("failed to add directory", "dir", dir, "err", err)
 		return err
 	}
+	f.mu.Lock()
 	f.directoryCount++
+	count := f.directoryCount
+	f.mu.Unlock()
+
 	if f.shouldLogWatchEvents {
-		f.Logger.Debug("ADDED to watchlist", "dir", dir, "count", f.directoryCount)
+		f.Logger.Debug("ADDED to watchlist", "dir", dir, "count", count)
 	}
 	return nil
 }
@@ -230,9 +632,54 @@ func (f *Watcher) Close() error {
 	return f.watcher.Close()
 }
 
+// WatchDir is a path to watch, along with its options
+type WatchDir struct {
+	Path string
+
+	// MaxDepth limits levels of files (similar to find's -maxdepth) watched under Path,
+	// 1 means only files directly inside Path, and 0 means unlimited
+	MaxDepth int
+}
+
+// ParseWatchDir parses a watch dir of form path[:option,...], where option is one of
+//   - depth=N: same as MaxDepth N (N >= 1)
+//   - non-recursive: same as MaxDepth 1
+//
+// A suffix after the last colon, that is not a list of options, is part of the path (like ./a:b)
+func ParseWatchDir(s string) (WatchDir, error) {
+	idx := strings.LastIndex(s, ":")
+	if idx == -1 {
+		return WatchDir{Path: s}, nil
+	}
+
+	opts := strings.Split(s[idx+1:], ",")
+	for _, opt := range opts {
+		if opt != "non-recursive" && !strings.HasPrefix(opt, "depth=") {
+			return WatchDir{Path: s}, nil
+		}
+	}
+
+	wd := WatchDir{Path: s[:idx]}
+	for _, opt := range opts {
+		switch {
+		case opt == "non-recursive":
+			wd.MaxDepth = 1
+		case strings.HasPrefix(opt, "depth="):
+			depth, err := strconv.Atoi(strings.TrimPrefix(opt, "depth="))
+			if err != nil || depth < 1 {
+				return WatchDir{}, fmt.Errorf("invalid depth (%s) for watch dir (%s), must be a positive integer", opt, s)
+			}
+			wd.MaxDepth = depth
+		}
+	}
+
+	return wd, nil
+}
+
 type WatcherArgs struct {
 	Logger *slog.Logger
 
+	// WatchDirs are paths to watch, each of them is parsed with ParseWatchDir
 	WatchDirs        []string
 	WatchExtensions  []string
 	IgnoreExtensions []string
@@ -243,6 +690,17 @@ type WatcherArgs struct {
 	CooldownDuration *time.Duration
 	Interactive      bool
 
+	// FollowSymlinks watches targets of symlinked directories, instead of skipping them
+	FollowSymlinks bool
+
+	// GoPackage (e.g. ./cmd/api) makes watcher watch only those directories and files, that the go package depends upon,
+	// they are computed with `go list -deps`, and get re-computed as go.mod, or go files change
+	GoPackage string
+
+	// WalkInBackground makes NewWatcher return without waiting for watch dirs to be walked,
+	// so that commands can start, while a large tree is still being walked. Use Ready() to wait for it.
+	WalkInBackground bool
+
 	ShouldLogWatchEvents bool
 }
 
@@ -261,6 +719,52 @@ var DefaultIgnoreExtensions = []string{
 }
 
 func NewWatcher(ctx context.Context, args WatcherArgs) (*Watcher, error) {
+	fsw, watchDirs, err := newWatcher(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		if errors.Is(err, syscall.EMFILE) {
+			err = fmt.Errorf("%w, inotify instance limit reached, %s", err, raiseLimitHint("max_user_instances", 0))
+		}
+		fsw.Logger.Error("failed to create watcher", "err", err)
+		return nil, err
+	}
+	fsw.watcher = watcher
+
+	if args.WalkInBackground {
+		go func() {
+			defer close(fsw.ready)
+			t := time.Now()
+			if err := fsw.addWatchDirs(watchDirs...); err != nil {
+				fsw.Logger.Error("failed to watch directories", "err", err)
+				return
+			}
+			fsw.mu.Lock()
+			count := fsw.directoryCount
+			fsw.mu.Unlock()
+			fsw.Logger.Debug("watching directories", "count", count, "took", time.Since(t).String())
+		}()
+		return fsw, nil
+	}
+
+	defer close(fsw.ready)
+	if err := fsw.addWatchDirs(watchDirs...); err != nil {
+		return nil, err
+	}
+
+	return fsw, nil
+}
+
+// Ready returns a channel, that gets closed once initial walk of watch dirs has completed
+func (f *Watcher) Ready() <-chan struct{} {
+	return f.ready
+}
+
+// newWatcher creates a watcher from args, without any underlying fsnotify watcher, and returns it along with its parsed watch dirs
+func newWatcher(ctx context.Context, args WatcherArgs) (*Watcher, []WatchDir, error) {
 	if args.Logger == nil {
 		args.Logger = slog.Default()
 	}
@@ -282,14 +786,33 @@ func NewWatcher(ctx context.Context, args WatcherArgs) (*Watcher, error) {
 		excludeDirs[dir] = struct{}{}
 	}
 
+	if args.WatchDirs == nil && args.GoPackage == "" {
+		dir, _ := os.Getwd()
+		args.WatchDirs = append(args.WatchDirs, dir)
+	}
+
+	watchDirs := make([]WatchDir, 0, len(args.WatchDirs))
 	for _, dir := range args.WatchDirs {
 		if args.ShouldLogWatchEvents {
 			args.Logger.Debug("watch-dirs", "dir", dir)
 		}
-		d := filepath.Base(dir)
+
+		wd, err := ParseWatchDir(dir)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		d := filepath.Base(wd.Path)
 		if strings.HasPrefix(d, "-") {
 			excludeDirs[d[1:]] = struct{}{}
 		}
+
+		// INFO: watch roots and targets are kept absolute, as a target (like, an env file) might lie in a watch root,
+		// and paths of both must match, for events of that root to be processed
+		if abs, err := filepath.Abs(wd.Path); err == nil {
+			wd.Path = abs
+		}
+		watchDirs = append(watchDirs, wd)
 	}
 
 	args.IgnoreExtensions = append(args.IgnoreExtensions, DefaultIgnoreExtensions...)
@@ -300,33 +823,39 @@ func NewWatcher(ctx context.Context, args WatcherArgs) (*Watcher, error) {
 		}
 	}
 
-	watcher, err := fsnotify.NewWatcher()
-	if err != nil {
-		args.Logger.Error("failed to create watcher", "err", err)
-		return nil, err
-	}
-
-	if args.WatchDirs == nil {
-		dir, _ := os.Getwd()
-		args.WatchDirs = append(args.WatchDirs, dir)
-	}
-
 	fsw := &Watcher{
-		watcher:          watcher,
+		ready:            make(chan struct{}),
 		Logger:           args.Logger,
 		ExcludeDirs:      excludeDirs,
 		IgnoreSuffixes:   args.IgnoreExtensions,
 		OnlySuffixes:     args.WatchExtensions,
 		cooldownDuration: cooldown,
-		watchingDirs:     make(map[string]struct{}),
+		watchingDirs:     make(map[string]int),
+		addingDirs:       make(map[string]struct{}),
+		watchTargets:     make(map[string]int),
+		parentDirs:       make(map[string]struct{}),
+
+		followSymlinks: args.FollowSymlinks,
+		realDirs:       make(map[string]string),
+		symlinkedDirs:  make(map[string]string),
 
 		shouldLogWatchEvents: args.ShouldLogWatchEvents,
 		eventsCh:             make(chan Event),
 	}
 
-	if err := fsw.RecursiveAdd(args.WatchDirs...); err != nil {
-		return nil, err
+	if args.GoPackage != "" {
+		graph, err := godeps.Load(ctx, "", args.GoPackage)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		fsw.golang = &goMode{pkg: args.GoPackage, graph: graph, refresh: make(chan struct{}, 1)}
+		watchDirs = append(watchDirs, goWatchDirs(graph)...)
 	}
 
-	return fsw, nil
+	for _, wd := range watchDirs {
+		fsw.roots = append(fsw.roots, wd.Path)
+	}
+
+	return fsw, watchDirs, nil
 }
@@ -0,0 +1,605 @@
+package watcher
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestWatcher(t *testing.T, args WatcherArgs) (*Watcher, context.CancelFunc) {
+	t.Helper()
+
+	cooldown := 0 * time.Millisecond
+	args.CooldownDuration = &cooldown
+
+	w, err := NewWatcher(context.TODO(), args)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cf := context.WithCancel(context.TODO())
+	go w.Watch(ctx)
+
+	return w, cf
+}
+
+func waitForEvent(t *testing.T, w *Watcher, timeout time.Duration) (Event, bool) {
+	t.Helper()
+
+	select {
+	case ev := <-w.GetEvents():
+		return ev, true
+	case <-time.After(timeout):
+		return Event{}, false
+	}
+}
+
+func Test_Watcher_NewDirectory(t *testing.T) {
+	root := t.TempDir()
+
+	w, cf := newTestWatcher(t, WatcherArgs{WatchDirs: []string{root}})
+	defer cf()
+
+	if err := os.MkdirAll(filepath.Join(root, "a", "b"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(root, "a", "b", "c.go"), []byte("package c"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	ev, ok := waitForEvent(t, w, 2*time.Second)
+	if !ok {
+		t.Fatalf("expected an event for file created inside a new directory, got none")
+	}
+
+	if want := filepath.Join(root, "a", "b", "c.go"); ev.Name != want {
+		t.Errorf("FAILED\n\t got: %s\n\twant: %s\n", ev.Name, want)
+	}
+}
+
+func Test_Watcher_FailedDirIsWalkedAgain(t *testing.T) {
+	root := t.TempDir()
+
+	w, cf := newTestWatcher(t, WatcherArgs{WatchDirs: []string{root}})
+	defer cf()
+
+	// INFO: like a directory, that got removed, after it was walked, but before it got added to the watch list
+	dir := filepath.Join(root, "a")
+	if err := w.watchDir(walkedDir{dir: dir, path: dir, depth: -1}); err == nil {
+		t.Fatalf("FAILED\n\t got: no error, adding a missing directory\n\twant: an error\n")
+	}
+
+	w.mu.Lock()
+	_, ok := w.watchingDirs[dir]
+	w.mu.Unlock()
+	if ok {
+		t.Fatalf("FAILED\n\t got: %s recorded as being watched\n\twant: not recorded\n", dir)
+	}
+
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	<-time.After(100 * time.Millisecond)
+
+	if err := os.WriteFile(filepath.Join(dir, "b.go"), []byte("package b"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	ev, ok := waitForEvent(t, w, 2*time.Second)
+	if !ok {
+		t.Fatalf("FAILED\n\t got: no event\n\twant: %s\n", filepath.Join(dir, "b.go"))
+	}
+
+	if want := filepath.Join(dir, "b.go"); ev.Name != want {
+		t.Errorf("FAILED\n\t got: %s\n\twant: %s\n", ev.Name, want)
+	}
+}
+
+func Test_Watcher_FollowSymlinks(t *testing.T) {
+	mkdir := func(t *testing.T, p string) {
+		if err := os.MkdirAll(p, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	symlink := func(t *testing.T, target, link string) {
+		if err := os.Symlink(target, link); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		// setup creates the tree, and returns file to write into, and the name event is expected to have
+		setup          func(t *testing.T, root, outside string) (write string, want string)
+		directoryCount int
+	}{
+		{
+			name: "1. symlink to a directory outside the watch root",
+			setup: func(t *testing.T, root, outside string) (string, string) {
+				mkdir(t, filepath.Join(outside, "pkg"))
+				symlink(t, filepath.Join(outside, "pkg"), filepath.Join(root, "link"))
+				return filepath.Join(outside, "pkg", "main.go"), filepath.Join(root, "link", "main.go")
+			},
+			directoryCount: 2,
+		},
+		{
+			name: "2. nested symlinks",
+			setup: func(t *testing.T, root, outside string) (string, string) {
+				mkdir(t, filepath.Join(outside, "a", "inner"))
+				mkdir(t, filepath.Join(outside, "b"))
+				symlink(t, filepath.Join(outside, "a"), filepath.Join(root, "link"))
+				symlink(t, filepath.Join(outside, "b"), filepath.Join(outside, "a", "inner", "nested"))
+				return filepath.Join(outside, "b", "main.go"), filepath.Join(root, "link", "inner", "nested", "main.go")
+			},
+			directoryCount: 4,
+		},
+		{
+			name: "3. symlink cycle",
+			setup: func(t *testing.T, root, outside string) (string, string) {
+				mkdir(t, filepath.Join(root, "a"))
+				symlink(t, filepath.Join(root, "a"), filepath.Join(root, "a", "loop"))
+				symlink(t, root, filepath.Join(root, "a", "up"))
+				return filepath.Join(root, "a", "main.go"), filepath.Join(root, "a", "main.go")
+			},
+			directoryCount: 2,
+		},
+		{
+			name: "4. symlinks pointing to each other",
+			setup: func(t *testing.T, root, outside string) (string, string) {
+				mkdir(t, filepath.Join(outside, "x"))
+				mkdir(t, filepath.Join(outside, "y"))
+				symlink(t, filepath.Join(outside, "y"), filepath.Join(outside, "x", "to-y"))
+				symlink(t, filepath.Join(outside, "x"), filepath.Join(outside, "y", "to-x"))
+				symlink(t, filepath.Join(outside, "x"), filepath.Join(root, "link"))
+				return filepath.Join(outside, "y", "main.go"), filepath.Join(root, "link", "to-y", "main.go")
+			},
+			directoryCount: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, err := filepath.EvalSymlinks(t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			outside, err := filepath.EvalSymlinks(t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			write, want := tt.setup(t, root, outside)
+
+			w, cf := newTestWatcher(t, WatcherArgs{WatchDirs: []string{root}, FollowSymlinks: true})
+			defer cf()
+
+			if w.directoryCount != tt.directoryCount {
+				t.Errorf("FAILED (%s), directory count\n\t got: %d\n\twant: %d\n", tt.name, w.directoryCount, tt.directoryCount)
+			}
+
+			if err := os.WriteFile(write, []byte("package main"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			ev, ok := waitForEvent(t, w, 2*time.Second)
+			if !ok {
+				t.Fatalf("FAILED (%s), expected an event, got none", tt.name)
+			}
+
+			if ev.Name != want {
+				t.Errorf("FAILED (%s)\n\t got: %s\n\twant: %s\n", tt.name, ev.Name, want)
+			}
+		})
+	}
+}
+
+func Test_Watcher_WatchTargets(t *testing.T) {
+	writeFile := func(t *testing.T, p string) {
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte("sample"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		// watch returns paths to watch, relative to root
+		watch []string
+		// writes are files (relative to root) written one after another, and want is list of events expected for those writes
+		writes []string
+		want   []string
+	}{
+		{
+			name:   "1. single file",
+			watch:  []string{".env"},
+			writes: []string{"other.txt", ".env"},
+			want:   []string{".env"},
+		},
+		{
+			name:   "2. single file, that does not exist yet",
+			watch:  []string{"config.yaml"},
+			writes: []string{"other.yaml", "config.yaml"},
+			want:   []string{"config.yaml"},
+		},
+		{
+			name:   "3. directory that does not exist yet",
+			watch:  []string{"dist"},
+			writes: []string{"other/app.js", "dist/app.js", "dist/assets/index.css"},
+			want:   []string{"dist/app.js", "dist/assets/index.css"},
+		},
+		{
+			name:   "4. nested directory that does not exist yet",
+			watch:  []string{"build/dist"},
+			writes: []string{"build/other.js", "build/dist/app.js"},
+			want:   []string{"build/dist/app.js"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			if err := os.WriteFile(filepath.Join(root, ".env"), []byte("A=1"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			watch := make([]string, 0, len(tt.watch))
+			for _, p := range tt.watch {
+				watch = append(watch, filepath.Join(root, p))
+			}
+
+			w, cf := newTestWatcher(t, WatcherArgs{WatchDirs: watch})
+			defer cf()
+
+			var got []string
+			for _, p := range tt.writes {
+				writeFile(t, filepath.Join(root, p))
+				// INFO: waiting, as each write is expected to be an individual event
+				for {
+					ev, ok := waitForEvent(t, w, 200*time.Millisecond)
+					if !ok {
+						break
+					}
+					rel, _ := filepath.Rel(root, ev.Name)
+					if len(got) == 0 || got[len(got)-1] != rel {
+						got = append(got, rel)
+					}
+				}
+			}
+
+			if g, w := strings.Join(got, ","), strings.Join(tt.want, ","); g != w {
+				t.Errorf("FAILED (%s)\n\t got: %s\n\twant: %s\n", tt.name, g, w)
+			}
+		})
+	}
+}
+
+func Test_Watcher_RelativeRootWithTarget(t *testing.T) {
+	root := t.TempDir()
+	envFile := filepath.Join(root, ".env")
+	if err := os.WriteFile(envFile, []byte("A=1"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(root); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	// INFO: like --env-file .env, with the default watch dir
+	w, cf := newTestWatcher(t, WatcherArgs{WatchDirs: []string{".", envFile}})
+	defer cf()
+
+	for _, name := range []string{"main.go", ".env"} {
+		if err := os.WriteFile(filepath.Join(root, name), []byte("sample"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		ev, ok := waitForEvent(t, w, 2*time.Second)
+		if !ok {
+			t.Fatalf("FAILED (%s)\n\t got: no event\n\twant: %s\n", name, filepath.Join(root, name))
+		}
+		if want := filepath.Join(root, name); ev.Name != want {
+			t.Errorf("FAILED (%s)\n\t got: %s\n\twant: %s\n", name, ev.Name, want)
+		}
+
+		// INFO: draining rest of the events of this write
+		for {
+			if _, ok := waitForEvent(t, w, 200*time.Millisecond); !ok {
+				break
+			}
+		}
+	}
+}
+
+func Test_Watcher_ExcludeDirs(t *testing.T) {
+	tests := []struct {
+		name string
+		// root is the watch root, relative to a temporary directory
+		root   string
+		ignore []string
+		// mkdirs are directories (relative to root), created after watcher has started
+		mkdirs []string
+		// writes are files (relative to root) written one after another, and want is list of events expected for those writes
+		writes []string
+		want   []string
+	}{
+		{
+			name:   "1. watch root, with an excluded name in its path",
+			root:   "me.github.io",
+			ignore: DefaultIgnoreList,
+			writes: []string{"index.html"},
+			want:   []string{"index.html"},
+		},
+		{
+			name:   "2. excluded directory, created below watch root",
+			root:   "me.github.io",
+			ignore: DefaultIgnoreList,
+			mkdirs: []string{"node_modules"},
+			writes: []string{"node_modules/index.js", "index.js"},
+			want:   []string{"index.js"},
+		},
+		{
+			name:   "3. excluded path, relative to watch root",
+			root:   "app",
+			ignore: []string{"./vendor/cache"},
+			mkdirs: []string{"vendor/cache"},
+			writes: []string{"vendor/cache/lib.go", "vendor/lib.go"},
+			want:   []string{"vendor/lib.go"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := filepath.Join(t.TempDir(), tt.root)
+			if err := os.MkdirAll(root, 0o755); err != nil {
+				t.Fatal(err)
+			}
+
+			w, cf := newTestWatcher(t, WatcherArgs{WatchDirs: []string{root}, IgnoreDirs: tt.ignore})
+			defer cf()
+
+			for _, d := range tt.mkdirs {
+				if err := os.MkdirAll(filepath.Join(root, d), 0o755); err != nil {
+					t.Fatal(err)
+				}
+			}
+			<-time.After(100 * time.Millisecond)
+
+			var got []string
+			for _, p := range tt.writes {
+				if err := os.WriteFile(filepath.Join(root, p), []byte("sample"), 0o644); err != nil {
+					t.Fatal(err)
+				}
+
+				for {
+					ev, ok := waitForEvent(t, w, 200*time.Millisecond)
+					if !ok {
+						break
+					}
+					rel, _ := filepath.Rel(root, ev.Name)
+					if len(got) == 0 || got[len(got)-1] != rel {
+						got = append(got, rel)
+					}
+				}
+			}
+
+			if g, w := strings.Join(got, ","), strings.Join(tt.want, ","); g != w {
+				t.Errorf("FAILED (%s)\n\t got: %s\n\twant: %s\n", tt.name, g, w)
+			}
+		})
+	}
+}
+
+func Test_ParseWatchDir(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    WatchDir
+		wantErr bool
+	}{
+		{input: "./configs", want: WatchDir{Path: "./configs"}},
+		{input: "./configs:depth=1", want: WatchDir{Path: "./configs", MaxDepth: 1}},
+		{input: "./configs:non-recursive", want: WatchDir{Path: "./configs", MaxDepth: 1}},
+		{input: "./configs:depth=3", want: WatchDir{Path: "./configs", MaxDepth: 3}},
+		{input: "./configs:depth=2,non-recursive", want: WatchDir{Path: "./configs", MaxDepth: 1}},
+		{input: "./configs:depth=0", wantErr: true},
+		{input: "./configs:depth=-1", wantErr: true},
+		{input: "./configs:depth=abc", wantErr: true},
+
+		// INFO: suffix, that is not a list of options, is part of the path
+		{input: "./a:b", want: WatchDir{Path: "./a:b"}},
+		{input: "./a:b:depth=2", want: WatchDir{Path: "./a:b", MaxDepth: 2}},
+		{input: "./configs:depth=2,unknown", want: WatchDir{Path: "./configs:depth=2,unknown"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseWatchDir(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FAILED (%s), unexpected error: %v", tt.input, err)
+			}
+
+			if got != tt.want {
+				t.Errorf("FAILED (%s)\n\t got: %+v\n\twant: %+v\n", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_Watcher_MaxDepth(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  string
+		watching []string
+		// created is a directory created after watcher starts, and shouldWatch tells whether it is expected to be watched
+		created     string
+		shouldWatch bool
+	}{
+		{
+			name:        "1. unlimited depth",
+			options:     "",
+			watching:    []string{".", "a", "a/b", "a/b/c"},
+			created:     "a/b/c/d",
+			shouldWatch: true,
+		},
+		{
+			name:        "2. non recursive",
+			options:     ":non-recursive",
+			watching:    []string{"."},
+			created:     "x",
+			shouldWatch: false,
+		},
+		{
+			name:        "3. depth=2",
+			options:     ":depth=2",
+			watching:    []string{".", "a"},
+			created:     "x",
+			shouldWatch: true,
+		},
+		{
+			name:        "4. depth=2, created beyond depth",
+			options:     ":depth=2",
+			watching:    []string{".", "a"},
+			created:     "a/x",
+			shouldWatch: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			if err := os.MkdirAll(filepath.Join(root, "a", "b", "c"), 0o755); err != nil {
+				t.Fatal(err)
+			}
+
+			w, cf := newTestWatcher(t, WatcherArgs{WatchDirs: []string{root + tt.options}})
+			defer cf()
+
+			if w.directoryCount != len(tt.watching) {
+				t.Errorf("FAILED (%s), directory count\n\t got: %d\n\twant: %d\n", tt.name, w.directoryCount, len(tt.watching))
+			}
+
+			for _, d := range tt.watching {
+				if _, ok := w.watchingDirs[filepath.Join(root, d)]; !ok {
+					t.Errorf("FAILED (%s), expected (%s) to be watched", tt.name, d)
+				}
+			}
+
+			if err := os.Mkdir(filepath.Join(root, tt.created), 0o755); err != nil {
+				t.Fatal(err)
+			}
+
+			// INFO: a file written into created directory, is reported only when that directory is being watched
+			if err := os.WriteFile(filepath.Join(root, tt.created, "sample.txt"), []byte("sample"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			_, ok := waitForEvent(t, w, 300*time.Millisecond)
+			if ok != tt.shouldWatch {
+				t.Errorf("FAILED (%s), event for file in created directory\n\t got: %v\n\twant: %v\n", tt.name, ok, tt.shouldWatch)
+			}
+		})
+	}
+}
+
+func Test_Diagnose(t *testing.T) {
+	root := t.TempDir()
+	for _, d := range []string{"a/b", "node_modules/x", "a/node_modules/y"} {
+		if err := os.MkdirAll(filepath.Join(root, d), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	d, err := Diagnose(WatcherArgs{
+		WatchDirs:  []string{root, filepath.Join(root, ".env")},
+		IgnoreList: DefaultIgnoreList,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if d.Directories != 3 {
+		t.Errorf("FAILED, directories\n\t got: %d\n\twant: %d\n", d.Directories, 3)
+	}
+
+	if len(d.IgnoredDirs) != 2 {
+		t.Errorf("FAILED, ignored directories\n\t got: %v\n\twant: %d of them\n", d.IgnoredDirs, 2)
+	}
+
+	if len(d.WatchTargets) != 1 || d.WatchTargets[0] != filepath.Join(root, ".env") {
+		t.Errorf("FAILED, watch targets\n\t got: %v\n\twant: %v\n", d.WatchTargets, []string{filepath.Join(root, ".env")})
+	}
+}
+
+// createTree creates a synthetic tree, with width directories at each level, up to depth levels
+func createTree(b *testing.B, root string, width, depth int) int {
+	if depth == 0 {
+		return 0
+	}
+
+	count := 0
+	for i := range width {
+		dir := filepath.Join(root, fmt.Sprintf("dir-%d", i))
+		if err := os.Mkdir(dir, 0o755); err != nil {
+			b.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main"), 0o644); err != nil {
+			b.Fatal(err)
+		}
+		count += 1 + createTree(b, dir, width, depth-1)
+	}
+
+	// INFO: ignored directories must not be descended into
+	if err := os.MkdirAll(filepath.Join(root, "node_modules", "pkg"), 0o755); err != nil {
+		b.Fatal(err)
+	}
+
+	return count
+}
+
+func Benchmark_Watcher_Startup(b *testing.B) {
+	root := b.TempDir()
+	count := createTree(b, root, 8, 4)
+	b.Logf("synthetic tree has %d directories", count+1)
+
+	args := WatcherArgs{
+		Logger:     slog.New(slog.NewTextHandler(io.Discard, nil)),
+		WatchDirs:  []string{root},
+		IgnoreList: DefaultIgnoreList,
+	}
+
+	b.Run("walk", func(b *testing.B) {
+		for range b.N {
+			d, err := Diagnose(args)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if d.Directories != count+1 {
+				b.Fatalf("expected %d directories, got %d", count+1, d.Directories)
+			}
+		}
+	})
+
+	b.Run("walk and watch", func(b *testing.B) {
+		for range b.N {
+			w, err := NewWatcher(context.TODO(), args)
+			if err != nil {
+				b.Fatal(err)
+			}
+			w.Close()
+		}
+	})
+}